@@ -0,0 +1,213 @@
+// Package catalog reads exercise guides from JSON and CSV files for bulk import
+package catalog
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fitness-backend/models"
+	"fitness-backend/utils"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+
+	// ListSeparator separates the items of list columns in CSV files
+	ListSeparator = "|"
+)
+
+// Columns are the CSV header names, matching the JSON field names of models.Exercise
+var Columns = []string{"name", "category", "mainMuscles", "difficulty", "benefits", "steps", "tips", "estimatedTime", "videoUrl", "met", "equipment"}
+
+// Row is one exercise read from an import file. Err is set when the row could not be read.
+type Row struct {
+	Line     int // 1-based record number: array index for JSON, line number for CSV
+	Exercise models.Exercise
+	Err      error
+}
+
+// FormatFromFilename guesses the format from a file extension, returning "" if unknown
+func FormatFromFilename(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		return FormatJSON
+	case ".csv":
+		return FormatCSV
+	default:
+		return ""
+	}
+}
+
+// Parse reads exercises in the given format. An error is returned only when the file as a
+// whole cannot be read; problems with single rows are reported on the row.
+func Parse(r io.Reader, format string) ([]Row, error) {
+	switch format {
+	case FormatJSON:
+		return parseJSON(r)
+	case FormatCSV:
+		return parseCSV(r)
+	default:
+		return nil, fmt.Errorf("unsupported format %q, expected json or csv", format)
+	}
+}
+
+// parseJSON reads an array of exercise objects
+func parseJSON(r io.Reader) ([]Row, error) {
+	var raw []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, errors.New("file must contain a JSON array of exercises")
+	}
+
+	rows := make([]Row, 0, len(raw))
+	for i, item := range raw {
+		row := Row{Line: i + 1}
+		if err := json.Unmarshal(item, &row.Exercise); err != nil {
+			row.Err = fmt.Errorf("invalid exercise: %v", err)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// parseCSV reads a file whose header names a subset of Columns. List columns hold their
+// items separated by ListSeparator.
+func parseCSV(r io.Reader) ([]Row, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.New("CSV file must start with a header row")
+	}
+	known := map[string]bool{}
+	for _, col := range Columns {
+		known[col] = true
+	}
+	for i, col := range header {
+		header[i] = strings.TrimSpace(col)
+		if !known[header[i]] {
+			return nil, fmt.Errorf("unknown CSV column %q", header[i])
+		}
+	}
+
+	var rows []Row
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		row := Row{Line: line}
+		if err != nil {
+			row.Err = err
+			rows = append(rows, row)
+			continue
+		}
+		if len(record) != len(header) {
+			row.Err = fmt.Errorf("expected %d columns, got %d", len(header), len(record))
+			rows = append(rows, row)
+			continue
+		}
+
+		for i, value := range record {
+			if err := setColumn(&row.Exercise, header[i], strings.TrimSpace(value)); err != nil {
+				row.Err = err
+				break
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// setColumn stores a CSV value on the matching exercise field
+func setColumn(exercise *models.Exercise, column, value string) error {
+	switch column {
+	case "name":
+		exercise.Name = value
+	case "category":
+		exercise.Category = value
+	case "mainMuscles":
+		exercise.MainMuscles = splitList(value)
+	case "difficulty":
+		exercise.Difficulty = value
+	case "benefits":
+		exercise.Benefits = splitList(value)
+	case "steps":
+		exercise.Steps = splitList(value)
+	case "tips":
+		exercise.Tips = splitList(value)
+	case "estimatedTime":
+		if value == "" {
+			return nil
+		}
+		duration, err := models.ParseDurationRange(value)
+		if err != nil {
+			return err
+		}
+		exercise.EstimatedTime = duration
+	case "equipment":
+		exercise.Equipment = models.NormalizeEquipment(splitList(value))
+	case "videoUrl":
+		exercise.VideoURL = value
+	case "met":
+		if value == "" {
+			return nil
+		}
+		met, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("met must be a number, got %q", value)
+		}
+		exercise.MET = met
+	}
+	return nil
+}
+
+// splitList splits a list column, dropping empty items
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ListSeparator) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// RowError describes why a row was not imported
+type RowError struct {
+	Line    int                `json:"line"`
+	Name    string             `json:"name,omitempty"`
+	Message string             `json:"message"`
+	Fields  []utils.FieldError `json:"fields,omitempty"`
+}
+
+// Report summarizes an import. In a dry run the counts describe what would have happened.
+type Report struct {
+	DryRun    bool       `json:"dryRun"`
+	Total     int        `json:"total"`
+	Created   int        `json:"created"`
+	Updated   int        `json:"updated"`
+	Unchanged int        `json:"unchanged"`
+	Failed    int        `json:"failed"`
+	Errors    []RowError `json:"errors"`
+}
+
+// Fail records a row that could not be imported
+func (r *Report) Fail(row Row, err error) {
+	r.Failed++
+	rowErr := RowError{Line: row.Line, Name: row.Exercise.Name, Message: err.Error()}
+	var fields utils.ValidationErrors
+	if errors.As(err, &fields) {
+		rowErr.Message = "Validation failed"
+		rowErr.Fields = fields
+	}
+	r.Errors = append(r.Errors, rowErr)
+}
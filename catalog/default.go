@@ -0,0 +1,15 @@
+package catalog
+
+import (
+	"bytes"
+	_ "embed"
+)
+
+//go:embed default_exercises.json
+var defaultExercises []byte
+
+// Default returns the exercise catalog bundled with the application, used to seed a fresh
+// deployment
+func Default() ([]Row, error) {
+	return Parse(bytes.NewReader(defaultExercises), FormatJSON)
+}
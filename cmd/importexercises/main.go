@@ -0,0 +1,87 @@
+// Command importexercises bulk imports exercise guides into the global catalog.
+//
+// Usage:
+//
+//	go run ./cmd/importexercises -file exercises.csv [-format csv] [-dry-run]
+//	go run ./cmd/importexercises -default [-dry-run]
+//
+// Guides are matched by name: existing ones are updated and the rest are created.
+// MONGODB_URI is read from the environment or .env, like the server.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fitness-backend/catalog"
+	"fitness-backend/controllers"
+	"fitness-backend/utils"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func main() {
+	file := flag.String("file", "", "JSON or CSV file to import")
+	format := flag.String("format", "", "file format, json or csv (default: from the file extension)")
+	useDefault := flag.Bool("default", false, "import the bundled default catalog")
+	dryRun := flag.Bool("dry-run", false, "report what would change without writing")
+	flag.Parse()
+
+	var rows []catalog.Row
+	var err error
+	switch {
+	case *useDefault && *file == "":
+		rows, err = catalog.Default()
+	case *file != "" && !*useDefault:
+		rows, err = readFile(*file, *format)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	utils.LoadEnv()
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(utils.GetEnvVariable("MONGODB_URI")))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Disconnect(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	controller := controllers.NewExerciseGuideController(client.Database("fitness"))
+	report, importErr := controller.Import(ctx, rows, *dryRun, primitive.NilObjectID)
+
+	out := json.NewEncoder(os.Stdout)
+	out.SetIndent("", "  ")
+	if err := out.Encode(report); err != nil {
+		log.Fatal(err)
+	}
+	if importErr != nil {
+		log.Fatal("Import stopped: ", importErr)
+	}
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// readFile parses an import file, taking the format from its extension when not given
+func readFile(path, format string) ([]catalog.Row, error) {
+	if format == "" {
+		format = catalog.FormatFromFilename(path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return catalog.Parse(f, format)
+}
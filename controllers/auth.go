@@ -1,10 +1,14 @@
 package controllers
 
 import (
+	"context"
+	"fitness-backend/mailer"
 	"fitness-backend/models"
 	"fitness-backend/utils"
+	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"go.mongodb.org/mongo-driver/bson"
@@ -14,12 +18,20 @@ import (
 )
 
 type AuthController struct {
-	collection *mongo.Collection
+	collection            *mongo.Collection
+	sessionCollection     *mongo.Collection
+	refreshCollection     *mongo.Collection
+	actionTokenCollection *mongo.Collection
+	Mailer                mailer.Mailer // Delivers password reset and verification emails; set from mailer.FromEnv at startup
 }
 
 func NewAuthController(db *mongo.Database) *AuthController {
 	return &AuthController{
-		collection: db.Collection("users"),
+		collection:            db.Collection("users"),
+		sessionCollection:     db.Collection("auth_sessions"),
+		refreshCollection:     db.Collection("refresh_tokens"),
+		actionTokenCollection: db.Collection("action_tokens"),
+		Mailer:                mailer.Unconfigured{},
 	}
 }
 
@@ -34,6 +46,9 @@ func (ac *AuthController) SignUp(c echo.Context) error {
 	if err := c.Bind(&user); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
+	if err := c.Validate(&user); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err))
+	}
 
 	var existingUser models.User
 	err := ac.collection.FindOne(c.Request().Context(), bson.M{"email": user.Email}).Decode(&existingUser)
@@ -43,6 +58,10 @@ func (ac *AuthController) SignUp(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Error checking existing user"})
 	}
 
+	// Roles and verification state are never taken from the request body
+	user.Verified = false
+	user.Role = models.RoleUser
+
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 	user.Password = string(hashedPassword)
 
@@ -51,8 +70,22 @@ func (ac *AuthController) SignUp(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Error creating user"})
 	}
 
-	token, _ := utils.GenerateToken(result.InsertedID.(primitive.ObjectID).Hex())
-	return c.JSON(http.StatusCreated, map[string]string{"token": token})
+	user.ID = result.InsertedID.(primitive.ObjectID)
+	// A failed verification email should not fail the signup; the user can ask for a resend
+	if err := ac.sendVerificationEmail(c.Request().Context(), user); err != nil {
+		log.Println("Error sending verification email:", err)
+	}
+
+	tokens, err := ac.startSession(c.Request().Context(), user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Error creating session"})
+	}
+	return c.JSON(http.StatusCreated, tokens)
+}
+
+type loginRequest struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
 }
 
 func (ac *AuthController) Login(c echo.Context) error {
@@ -62,10 +95,13 @@ func (ac *AuthController) Login(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusUnsupportedMediaType, "Content-Type must be application/json")
 	}
 
-	var loginUser models.User
+	var loginUser loginRequest
 	if err := c.Bind(&loginUser); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
+	if err := c.Validate(&loginUser); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err))
+	}
 
 	var user models.User
 	err := ac.collection.FindOne(c.Request().Context(), bson.M{"email": loginUser.Email}).Decode(&user)
@@ -77,8 +113,18 @@ func (ac *AuthController) Login(c echo.Context) error {
 		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid credentials"})
 	}
 
-	token, _ := utils.GenerateToken(user.ID.Hex())
-	return c.JSON(http.StatusOK, map[string]string{"token": token})
+	// ADMIN_EMAILS only applies once the address is verified, so nobody can claim it by signing up first
+	if user.Verified && user.EffectiveRole() != models.RoleAdmin && isBootstrapAdmin(user.Email) {
+		if _, err := ac.collection.UpdateOne(c.Request().Context(), bson.M{"_id": user.ID}, bson.M{"$set": bson.M{"role": models.RoleAdmin}}); err == nil {
+			user.Role = models.RoleAdmin
+		}
+	}
+
+	tokens, err := ac.startSession(c.Request().Context(), user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Error creating session"})
+	}
+	return c.JSON(http.StatusOK, tokens)
 }
 
 func (ac *AuthController) GetUserDetails(c echo.Context) error {
@@ -102,6 +148,193 @@ func (ac *AuthController) GetUserDetails(c echo.Context) error {
 
 	// Remove sensitive information
 	user.Password = ""
+	user.Role = user.EffectiveRole()
 
 	return c.JSON(http.StatusOK, user)
 }
+
+type refreshRequest struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
+
+// Refresh rotates a refresh token. Presenting a token that was already rotated
+// is treated as theft and revokes the whole session.
+func (ac *AuthController) Refresh(c echo.Context) error {
+	var req refreshRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid request body"))
+	}
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err))
+	}
+
+	ctx := c.Request().Context()
+	now := time.Now()
+	hash := utils.HashToken(req.RefreshToken)
+
+	// Mark the token as used atomically so concurrent refreshes cannot both succeed
+	var stored models.RefreshToken
+	err := ac.refreshCollection.FindOneAndUpdate(ctx,
+		bson.M{"tokenHash": hash, "usedAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"usedAt": now}},
+	).Decode(&stored)
+	if err == mongo.ErrNoDocuments {
+		var reused models.RefreshToken
+		if ac.refreshCollection.FindOne(ctx, bson.M{"tokenHash": hash}).Decode(&reused) == nil {
+			if err := ac.revokeSession(ctx, reused.SessionID, "reuse"); err != nil {
+				return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error revoking session"))
+			}
+			return c.JSON(http.StatusUnauthorized, utils.ErrorResponse("Refresh token reuse detected"))
+		}
+		return c.JSON(http.StatusUnauthorized, utils.ErrorResponse("Invalid refresh token"))
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error reading refresh token"))
+	}
+
+	if now.After(stored.ExpiresAt) {
+		return c.JSON(http.StatusUnauthorized, utils.ErrorResponse("Refresh token expired"))
+	}
+
+	var session models.AuthSession
+	err = ac.sessionCollection.FindOne(ctx, bson.M{"_id": stored.SessionID}).Decode(&session)
+	if err != nil || session.RevokedAt != nil {
+		return c.JSON(http.StatusUnauthorized, utils.ErrorResponse("Session revoked"))
+	}
+
+	var user models.User
+	if err := ac.collection.FindOne(ctx, bson.M{"_id": stored.UserID}).Decode(&user); err != nil {
+		return c.JSON(http.StatusUnauthorized, utils.ErrorResponse("User not found"))
+	}
+
+	tokens, err := ac.issueTokens(ctx, user, session.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error issuing tokens"))
+	}
+	return c.JSON(http.StatusOK, tokens)
+}
+
+// Logout revokes the session the refresh token belongs to
+func (ac *AuthController) Logout(c echo.Context) error {
+	var req refreshRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid request body"))
+	}
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err))
+	}
+
+	ctx := c.Request().Context()
+	var stored models.RefreshToken
+	err := ac.refreshCollection.FindOne(ctx, bson.M{"tokenHash": utils.HashToken(req.RefreshToken)}).Decode(&stored)
+	if err == mongo.ErrNoDocuments {
+		return c.JSON(http.StatusUnauthorized, utils.ErrorResponse("Invalid refresh token"))
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error reading refresh token"))
+	}
+
+	if err := ac.revokeSession(ctx, stored.SessionID, "logout"); err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error revoking session"))
+	}
+	return c.JSON(http.StatusOK, utils.SuccessResponse("Logged out successfully"))
+}
+
+// startSession creates a new token family for the user and issues its first tokens
+func (ac *AuthController) startSession(ctx context.Context, user models.User) (echo.Map, error) {
+	session := models.AuthSession{
+		ID:        primitive.NewObjectID(),
+		UserID:    user.ID,
+		CreatedAt: time.Now(),
+	}
+	if _, err := ac.sessionCollection.InsertOne(ctx, session); err != nil {
+		return nil, err
+	}
+	return ac.issueTokens(ctx, user, session.ID)
+}
+
+// issueTokens signs an access token and stores a fresh refresh token for the session
+func (ac *AuthController) issueTokens(ctx context.Context, user models.User, sessionID primitive.ObjectID) (echo.Map, error) {
+	accessToken, err := utils.GenerateToken(utils.AccessTokenClaims{
+		UserID:    user.ID.Hex(),
+		SessionID: sessionID.Hex(),
+		Role:      user.EffectiveRole(),
+		Verified:  user.Verified,
+		Timezone:  user.Timezone,
+		Units:     user.Units,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	_, err = ac.refreshCollection.InsertOne(ctx, models.RefreshToken{
+		ID:        primitive.NewObjectID(),
+		SessionID: sessionID,
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(refreshToken),
+		ExpiresAt: now.Add(utils.RefreshTokenTTL),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return echo.Map{
+		"token":        accessToken,
+		"refreshToken": refreshToken,
+		"expiresIn":    int(utils.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+// revokeSession marks a session as revoked; access tokens carrying its id stop working immediately
+func (ac *AuthController) revokeSession(ctx context.Context, sessionID primitive.ObjectID, reason string) error {
+	_, err := ac.sessionCollection.UpdateOne(ctx,
+		bson.M{"_id": sessionID, "revokedAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revokedAt": time.Now(), "revokedReason": reason}},
+	)
+	return err
+}
+
+// UpdateUserRole lets an admin change another user's role
+func (ac *AuthController) UpdateUserRole(c echo.Context) error {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid user ID"))
+	}
+
+	var req struct {
+		Role string `json:"role" validate:"required,oneof=user coach admin"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid request body"))
+	}
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err))
+	}
+
+	result, err := ac.collection.UpdateOne(c.Request().Context(), bson.M{"_id": objectID}, bson.M{"$set": bson.M{"role": req.Role}})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error updating role"))
+	}
+	if result.MatchedCount == 0 {
+		return c.JSON(http.StatusNotFound, utils.ErrorResponse("User not found"))
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"id": objectID, "role": req.Role})
+}
+
+// isBootstrapAdmin reports whether the email is listed in ADMIN_EMAILS, which is how the first admins are created.
+// Accounts are promoted when they verify the address, or at login if already verified.
+func isBootstrapAdmin(email string) bool {
+	for _, admin := range strings.Split(utils.GetEnvVariable("ADMIN_EMAILS"), ",") {
+		admin = strings.TrimSpace(admin)
+		if admin != "" && strings.EqualFold(admin, email) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"context"
+	"fitness-backend/models"
+	"fitness-backend/utils"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// goalCalories is the calorie estimate for one exercise goal. CaloriesBurned is nil when
+// the goal cannot be estimated, e.g. its exercise has no MET value.
+type goalCalories struct {
+	GoalID         interface{}        `json:"goalId"`
+	GoalName       string             `json:"goalName"`
+	ExerciseID     primitive.ObjectID `json:"exerciseId"`
+	Type           string             `json:"type"`
+	ProgressValue  float64            `json:"progressValue"`
+	Intensity      string             `json:"intensity,omitempty"`
+	MET            float64            `json:"met,omitempty"`
+	CaloriesBurned *float64           `json:"caloriesBurned"`
+}
+
+// calorieBurnReport lists the calorie estimates for a day's exercise goals
+type calorieBurnReport struct {
+	Total   float64        `json:"caloriesBurned"`
+	Goals   []goalCalories `json:"exercises"`
+	Missing []string       `json:"missing,omitempty"` // Profile fields needed for an estimate
+}
+
+// exerciseMET returns the MET for an exercise performed at an intensity, falling back to
+// the exercise's general MET
+func exerciseMET(exercise models.Exercise, intensity string) float64 {
+	if met, ok := exercise.IntensityMETs[intensity]; ok && met > 0 {
+		return met
+	}
+	return exercise.MET
+}
+
+// onFootWords mark exercises that cover distance on foot, whose cost per km barely depends
+// on speed
+var onFootWords = map[string]bool{
+	"run": true, "running": true, "jog": true, "jogging": true, "sprint": true, "sprints": true,
+	"walk": true, "walking": true, "hike": true, "hiking": true, "treadmill": true,
+}
+
+// movesOnFoot reports whether an exercise is running or walking, judged by its name
+func movesOnFoot(exercise models.Exercise) bool {
+	for _, word := range strings.Fields(strings.ToLower(exercise.Name)) {
+		if onFootWords[word] {
+			return true
+		}
+	}
+	return false
+}
+
+// goalPace returns the minutes per km of the goal's sets that log both distance and
+// duration, or 0 when none do
+func goalPace(goalMap map[string]interface{}) float64 {
+	data, err := bson.Marshal(goalMap)
+	if err != nil {
+		return 0
+	}
+	var goal models.ExerciseGoal
+	if err := bson.Unmarshal(data, &goal); err != nil {
+		return 0
+	}
+	var km, seconds float64
+	for _, set := range goal.Sets {
+		if set.Distance > 0 && set.DurationSeconds > 0 {
+			km += set.Distance
+			seconds += float64(set.DurationSeconds)
+		}
+	}
+	if km == 0 {
+		return 0
+	}
+	return seconds / 60 / km
+}
+
+// goalCaloriesBurned estimates the kcal burned by the progress made on an exercise goal.
+// Time-based goals use MET x weight x hours and reps are converted to time. Distance is
+// converted to time at the pace of the goal's timed sets when the exercise has a MET;
+// without a pace only running and walking can be estimated, from the per-km cost of moving
+// the body. It reports false when no estimate is possible.
+func goalCaloriesBurned(goalType string, progress, met, weightKg, minutesPerKm float64, onFoot bool) (float64, bool) {
+	switch goalType {
+	case "mins":
+		return utils.CaloriesFromMET(met, weightKg, progress), met > 0
+	case "reps":
+		return utils.CaloriesFromMET(met, weightKg, utils.RepsToMinutes(progress)), met > 0
+	case "kms":
+		if met > 0 && minutesPerKm > 0 {
+			return utils.CaloriesFromMET(met, weightKg, progress*minutesPerKm), true
+		}
+		if onFoot {
+			return utils.CaloriesFromDistance(weightKg, progress), true
+		}
+		return 0, false
+	default:
+		return 0, false
+	}
+}
+
+// estimateCaloriesBurned estimates the calories burned by a day's exercise goals using the
+// user's weight and each linked exercise's MET
+func (pc *ProgressController) estimateCaloriesBurned(ctx context.Context, userID primitive.ObjectID, goals []interface{}) (calorieBurnReport, error) {
+	report := calorieBurnReport{Goals: []goalCalories{}}
+
+	var user models.User
+	err := pc.UserCollection.FindOne(ctx, bson.M{"_id": userID}, options.FindOne().SetProjection(bson.M{"weight": 1})).Decode(&user)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return report, err
+	}
+	if user.Weight <= 0 {
+		report.Missing = []string{"weight"}
+	}
+
+	var exerciseGoals []map[string]interface{}
+	var exerciseIDs []primitive.ObjectID
+	for _, goal := range goals {
+		goalMap, ok := goalAsMap(goal)
+		if !ok {
+			continue
+		}
+		exerciseID, ok := goalMap["exerciseId"].(primitive.ObjectID)
+		if !ok {
+			continue
+		}
+		exerciseGoals = append(exerciseGoals, goalMap)
+		if !exerciseID.IsZero() {
+			exerciseIDs = append(exerciseIDs, exerciseID)
+		}
+	}
+
+	// Soft-deleted guides still count for goals that were linked to them
+	exercises := map[primitive.ObjectID]models.Exercise{}
+	if len(exerciseIDs) > 0 {
+		cursor, err := pc.ExerciseCollection.Find(ctx, bson.M{"_id": bson.M{"$in": exerciseIDs}})
+		if err != nil {
+			return report, err
+		}
+		var found []models.Exercise
+		if err := cursor.All(ctx, &found); err != nil {
+			return report, err
+		}
+		for _, exercise := range found {
+			exercises[exercise.ID] = exercise
+		}
+	}
+
+	for _, goalMap := range exerciseGoals {
+		exerciseID := goalMap["exerciseId"].(primitive.ObjectID)
+		entry := goalCalories{GoalID: goalMap["_id"], ExerciseID: exerciseID}
+		entry.GoalName, _ = goalMap["goalName"].(string)
+		entry.Type, _ = goalMap["type"].(string)
+		entry.Intensity, _ = goalMap["intensity"].(string)
+		entry.ProgressValue, _ = utils.ConvertToFloat(goalMap["progressValue"])
+
+		onFoot := false
+		if exercise, ok := exercises[exerciseID]; ok {
+			entry.MET = exerciseMET(exercise, entry.Intensity)
+			onFoot = movesOnFoot(exercise)
+		}
+		if user.Weight > 0 {
+			if kcal, ok := goalCaloriesBurned(entry.Type, entry.ProgressValue, entry.MET, user.Weight, goalPace(goalMap), onFoot); ok {
+				kcal = utils.Round(kcal, 1)
+				entry.CaloriesBurned = &kcal
+				report.Total += kcal
+			}
+		}
+		report.Goals = append(report.Goals, entry)
+	}
+
+	report.Total = utils.Round(report.Total, 1)
+	return report, nil
+}
@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"context"
+	"fitness-backend/models"
+	"fitness-backend/utils"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// customExerciseRequest describes a private exercise. Only a name and category are required
+// since users mostly record movements the catalog is missing.
+type customExerciseRequest struct {
+	Name          string                `json:"name" validate:"required"`
+	Category      string                `json:"category" validate:"required"`
+	MainMuscles   []string              `json:"mainMuscles" validate:"omitempty,dive,required"`
+	Difficulty    string                `json:"difficulty"`
+	Benefits      []string              `json:"benefits" validate:"omitempty,dive,required"`
+	Steps         []string              `json:"steps" validate:"omitempty,dive,required"`
+	Tips          []string              `json:"tips" validate:"omitempty,dive,required"`
+	EstimatedTime *models.DurationRange `json:"estimatedTime" validate:"omitnil"`
+	Equipment     []string              `json:"equipment" validate:"omitempty,dive,required"`
+	VideoURL      string                `json:"videoUrl" validate:"omitempty,url"`
+	MET           float64               `json:"met" validate:"omitempty,gt=0,lte=25"`
+	IntensityMETs map[string]float64    `json:"intensityMets" validate:"omitempty,dive,keys,oneof=light moderate vigorous,endkeys,gt=0,lte=25"`
+}
+
+// CreateCustomExercise adds a private exercise visible only to the authenticated user
+func (ec *ExerciseGuideController) CreateCustomExercise(c echo.Context) error {
+	userID := actorID(c)
+	if userID.IsZero() {
+		return c.JSON(http.StatusUnauthorized, echo.Map{"error": "Unauthorized"})
+	}
+
+	var req customExerciseRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid input", "details": err.Error()})
+	}
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Goals link to exercises by name, so a user's own names must be unique
+	count, err := ec.collection.CountDocuments(ctx, bson.M{"ownerId": userID, "name": req.Name, "deletedAt": nil})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to create exercise"})
+	}
+	if count > 0 {
+		return c.JSON(http.StatusConflict, echo.Map{"error": "You already have an exercise with this name"})
+	}
+
+	exercise := models.Exercise{
+		ID:            primitive.NewObjectID(),
+		OwnerID:       &userID,
+		Name:          req.Name,
+		Category:      req.Category,
+		MainMuscles:   emptyIfNil(req.MainMuscles),
+		Difficulty:    req.Difficulty,
+		Benefits:      emptyIfNil(req.Benefits),
+		Steps:         emptyIfNil(req.Steps),
+		Tips:          emptyIfNil(req.Tips),
+		Equipment:     models.NormalizeEquipment(req.Equipment),
+		VideoURL:      req.VideoURL,
+		MET:           req.MET,
+		IntensityMETs: req.IntensityMETs,
+	}
+
+	if req.EstimatedTime != nil {
+		exercise.EstimatedTime = *req.EstimatedTime
+	}
+
+	if _, err := ec.collection.InsertOne(ctx, exercise); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to create exercise"})
+	}
+
+	if _, err := ec.recordRevision(ctx, exercise, models.RevisionCreate, []string{}, userID, 0); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to record revision"})
+	}
+
+	return c.JSON(http.StatusCreated, echo.Map{
+		"message":  "Exercise created successfully",
+		"exercise": exercise,
+		"id":       exercise.ID,
+	})
+}
+
+// PatchCustomExercise updates one of the authenticated user's private exercises
+func (ec *ExerciseGuideController) PatchCustomExercise(c echo.Context) error {
+	filter, err := ownedExerciseFilter(c)
+	if err != nil {
+		return err
+	}
+
+	var patch exercisePatchRequest
+	if err := c.Bind(&patch); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid input", "details": err.Error()})
+	}
+	if err := c.Validate(&patch); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err))
+	}
+
+	if patch.Name != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		count, err := ec.collection.CountDocuments(ctx, bson.M{
+			"ownerId":   filter["ownerId"],
+			"name":      *patch.Name,
+			"deletedAt": nil,
+			"_id":       bson.M{"$ne": filter["_id"]},
+		})
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to update exercise"})
+		}
+		if count > 0 {
+			return c.JSON(http.StatusConflict, echo.Map{"error": "You already have an exercise with this name"})
+		}
+	}
+
+	return ec.saveExerciseChange(c, filter, patch.apply)
+}
+
+// DeleteCustomExercise removes one of the authenticated user's private exercises
+func (ec *ExerciseGuideController) DeleteCustomExercise(c echo.Context) error {
+	filter, err := ownedExerciseFilter(c)
+	if err != nil {
+		return err
+	}
+	return ec.deleteExercise(c, filter)
+}
+
+// ownedExerciseFilter matches the exercise named by :id when the authenticated user owns it,
+// returning an *echo.HTTPError on failure
+func ownedExerciseFilter(c echo.Context) (bson.M, error) {
+	userID := actorID(c)
+	if userID.IsZero() {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, utils.ErrorResponse("Unauthorized"))
+	}
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, utils.ErrorResponse("Invalid exercise ID"))
+	}
+	return bson.M{"_id": objID, "ownerId": userID}, nil
+}
+
+// findExerciseForUser resolves an exercise name to the user's private exercise of that name,
+// falling back to the global catalog
+func findExerciseForUser(ctx context.Context, collection *mongo.Collection, userID primitive.ObjectID, name string) (models.Exercise, error) {
+	var exercise models.Exercise
+	err := collection.FindOne(ctx, bson.M{"ownerId": userID, "name": name, "deletedAt": nil}).Decode(&exercise)
+	if err != mongo.ErrNoDocuments {
+		return exercise, err
+	}
+	err = collection.FindOne(ctx, bson.M{"ownerId": nil, "name": name, "deletedAt": nil}).Decode(&exercise)
+	return exercise, err
+}
+
+// emptyIfNil keeps optional lists serialized as [] rather than null
+func emptyIfNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
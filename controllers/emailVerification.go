@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"context"
+	"fitness-backend/mailer"
+	"fitness-backend/models"
+	"fitness-backend/utils"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const emailVerificationTTL = 48 * time.Hour
+
+// VerifyEmail consumes a verification token and marks the account as verified
+func (ac *AuthController) VerifyEmail(c echo.Context) error {
+	token := c.QueryParam("token")
+	if token == "" {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("token is required"))
+	}
+
+	ctx := c.Request().Context()
+	verification, err := ac.consumeActionToken(ctx, token, models.TokenPurposeEmailVerification)
+	if err == mongo.ErrNoDocuments {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid or expired verification token"))
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error reading verification token"))
+	}
+
+	// The token is only valid for the address it was sent to. Proving ownership of an
+	// address listed in ADMIN_EMAILS is what makes its account an admin.
+	set := bson.M{"verified": true}
+	if isBootstrapAdmin(verification.Email) {
+		set["role"] = models.RoleAdmin
+	}
+	result, err := ac.collection.UpdateOne(ctx,
+		bson.M{"_id": verification.UserID, "email": verification.Email},
+		bson.M{"$set": set},
+	)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error verifying email"))
+	}
+	if result.MatchedCount == 0 {
+		return c.JSON(http.StatusNotFound, utils.ErrorResponse("User not found"))
+	}
+
+	return c.JSON(http.StatusOK, utils.SuccessResponse("Email verified successfully"))
+}
+
+// ResendVerification sends a fresh verification email. Like ForgotPassword it answers
+// identically for unknown, already verified and rate-limited addresses.
+func (ac *AuthController) ResendVerification(c echo.Context) error {
+	var req struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid request body"))
+	}
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err))
+	}
+
+	ctx := c.Request().Context()
+	response := utils.SuccessResponse("If this email needs verification, a new link has been sent")
+
+	limited, err := ac.actionTokenRateLimited(ctx, req.Email, models.TokenPurposeEmailVerification)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error checking verification requests"))
+	}
+	if limited {
+		return c.JSON(http.StatusOK, response)
+	}
+
+	var user models.User
+	err = ac.collection.FindOne(ctx, bson.M{"email": req.Email}).Decode(&user)
+	if err == mongo.ErrNoDocuments || (err == nil && user.Verified) {
+		return c.JSON(http.StatusOK, response)
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error fetching user"))
+	}
+
+	if err := ac.sendVerificationEmail(ctx, user); err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error sending verification email"))
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// sendVerificationEmail creates a verification token for the user and mails it
+func (ac *AuthController) sendVerificationEmail(ctx context.Context, user models.User) error {
+	token, err := ac.createActionToken(ctx, user, models.TokenPurposeEmailVerification, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Use this code to verify your email address: %s\n", token)
+	if verifyURL := utils.GetEnvVariable("EMAIL_VERIFICATION_URL"); verifyURL != "" {
+		body += fmt.Sprintf("\nOr open %s?token=%s\n", verifyURL, token)
+	}
+	body += fmt.Sprintf("\nThe code expires in %d hours.", int(emailVerificationTTL.Hours()))
+
+	return ac.Mailer.Send(ctx, mailer.Message{To: user.Email, Subject: "Verify your email address", Body: body, Secrets: []string{token}})
+}
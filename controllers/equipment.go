@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"context"
+	"fitness-backend/models"
+	"fitness-backend/utils"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// equipmentProfileRequest replaces the equipment available at one location
+type equipmentProfileRequest struct {
+	Equipment []string `json:"equipment" validate:"max=50,dive,required,max=50"`
+}
+
+// GetEquipmentProfiles returns the equipment the user has at each location
+func (uc *UserController) GetEquipmentProfiles(c echo.Context) error {
+	user, err := uc.currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	profiles := map[string][]string{}
+	for _, location := range []string{models.LocationHome, models.LocationGym, models.LocationTravel} {
+		if equipment, ok := user.EquipmentProfiles[location]; ok {
+			profiles[location] = emptyIfNil(equipment)
+		}
+	}
+	return c.JSON(http.StatusOK, profiles)
+}
+
+// UpdateEquipmentProfile sets the equipment the user has at a location. An empty list
+// means bodyweight exercises only.
+func (uc *UserController) UpdateEquipmentProfile(c echo.Context) error {
+	userID := actorID(c)
+	if userID.IsZero() {
+		return c.JSON(http.StatusUnauthorized, utils.ErrorResponse("Unauthorized"))
+	}
+
+	location := c.Param("location")
+	if !models.IsValidLocation(location) {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("location must be one of home, gym or travel"))
+	}
+
+	var req equipmentProfileRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid request body"))
+	}
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	equipment := emptyIfNil(models.NormalizeEquipment(req.Equipment))
+	result, err := uc.collection.UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{"$set": bson.M{"equipmentProfiles." + location: equipment, "updatedAt": time.Now()}},
+	)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to update equipment profile"))
+	}
+	if result.MatchedCount == 0 {
+		return c.JSON(http.StatusNotFound, utils.ErrorResponse("User not found"))
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"location": location, "equipment": equipment})
+}
+
+// equipmentFilter matches exercises that need nothing beyond the equipment in the user's
+// profile for a location, returning an *echo.HTTPError when there is no such profile
+func equipmentFilter(ctx context.Context, users *mongo.Collection, userID primitive.ObjectID, location string) (bson.M, error) {
+	if !models.IsValidLocation(location) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, utils.ErrorResponse("location must be one of home, gym or travel"))
+	}
+
+	var user models.User
+	err := users.FindOne(ctx, bson.M{"_id": userID}, options.FindOne().SetProjection(bson.M{"equipmentProfiles": 1})).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return nil, echo.NewHTTPError(http.StatusNotFound, utils.ErrorResponse("User not found"))
+	} else if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, utils.ErrorResponse("Failed to fetch equipment profile"))
+	}
+
+	available, ok := user.EquipmentProfiles[location]
+	if !ok {
+		return nil, echo.NewHTTPError(http.StatusUnprocessableEntity, utils.ErrorResponse("No equipment profile for "+location+"; set one with PUT /api/user/equipment/"+location))
+	}
+
+	// No required item may be missing from the profile. Bodyweight exercises store an empty
+	// list; exercises with no list, or a null one, have unknown equipment and are left out.
+	return bson.M{"equipment": bson.M{
+		"$type": "array",
+		"$not":  bson.M{"$elemMatch": bson.M{"$nin": emptyIfNil(available)}},
+	}}, nil
+}
@@ -0,0 +1,300 @@
+package controllers
+
+import (
+	"context"
+	"fitness-backend/models"
+	"fitness-backend/utils"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const defaultAlternativesLimit = 10
+
+// Weights of each kind of overlap in an alternative's score, summing to 1
+const (
+	muscleOverlapWeight = 0.5
+	categoryWeight      = 0.2
+	difficultyWeight    = 0.15
+	equipmentWeight     = 0.15
+)
+
+// difficultyLevels orders difficulties so neighbouring levels score as close matches
+var difficultyLevels = map[string]int{
+	"beginner":     1,
+	"intermediate": 2,
+	"advanced":     3,
+}
+
+// exerciseVariationResult is a curated variation with the exercise it points to
+type exerciseVariationResult struct {
+	Relation string          `json:"relation"`
+	Exercise models.Exercise `json:"exercise"`
+}
+
+// exerciseAlternative is a ranked substitute with what it has in common with the original
+type exerciseAlternative struct {
+	Exercise       models.Exercise `json:"exercise"`
+	Score          float64         `json:"score"`
+	SharedMuscles  []string        `json:"sharedMuscles"`
+	SameCategory   bool            `json:"sameCategory"`
+	SameDifficulty bool            `json:"sameDifficulty"`
+}
+
+// GetExerciseAlternatives suggests substitutes for an exercise: the curated variations
+// linked to it, then other exercises ranked by overlap in muscles, category, difficulty
+// and equipment. Supports ?limit= and ?location= (only exercises the user can do there).
+func (ec *ExerciseGuideController) GetExerciseAlternatives(c echo.Context) error {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid exercise ID"})
+	}
+
+	limit := defaultAlternativesLimit
+	if l := c.QueryParam("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 || n > maxExercisePageSize {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid limit"})
+		}
+		limit = n
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	userID := actorID(c)
+	var exercise models.Exercise
+	err = ec.collection.FindOne(ctx, bson.M{"$and": bson.A{bson.M{"_id": objID}, exerciseVisibility(userID)}}).Decode(&exercise)
+	if err == mongo.ErrNoDocuments {
+		return c.JSON(http.StatusNotFound, echo.Map{"error": "Exercise not found"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to fetch exercise"})
+	}
+
+	// Candidates share the category or at least one muscle, and exclude the exercise itself
+	similar := bson.A{bson.M{"category": exercise.Category}}
+	if len(exercise.MainMuscles) > 0 {
+		similar = append(similar, bson.M{"mainMuscles": bson.M{"$in": exercise.MainMuscles}})
+	}
+	conditions := bson.A{
+		exerciseVisibility(userID),
+		bson.M{"_id": bson.M{"$ne": exercise.ID}, "deletedAt": nil},
+	}
+	if location := c.QueryParam("location"); location != "" {
+		filter, err := equipmentFilter(ctx, ec.userCollection, userID, location)
+		if err != nil {
+			return err
+		}
+		conditions = append(conditions, filter)
+	}
+
+	variations, err := ec.findVariations(ctx, exercise, bson.M{"$and": conditions})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to fetch variations"})
+	}
+
+	cursor, err := ec.collection.Find(ctx, bson.M{"$and": append(conditions, bson.M{"$or": similar})})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to fetch alternatives"})
+	}
+	var candidates []models.Exercise
+	if err := cursor.All(ctx, &candidates); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Error decoding exercises"})
+	}
+
+	curated := map[primitive.ObjectID]bool{}
+	for _, v := range variations {
+		curated[v.Exercise.ID] = true
+	}
+
+	alternatives := make([]exerciseAlternative, 0, len(candidates))
+	for _, candidate := range candidates {
+		if curated[candidate.ID] {
+			continue
+		}
+		alternatives = append(alternatives, scoreAlternative(exercise, candidate))
+	}
+	sort.SliceStable(alternatives, func(i, j int) bool { return alternatives[i].Score > alternatives[j].Score })
+	if len(alternatives) > limit {
+		alternatives = alternatives[:limit]
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"exerciseId":   exercise.ID,
+		"variations":   variations,
+		"alternatives": alternatives,
+	})
+}
+
+// findVariations returns the curated variations of an exercise that match filter: the links
+// stored on the exercise and, inverted, the links other exercises hold to it
+func (ec *ExerciseGuideController) findVariations(ctx context.Context, exercise models.Exercise, filter bson.M) ([]exerciseVariationResult, error) {
+	relations := map[primitive.ObjectID]string{}
+	ids := []primitive.ObjectID{}
+	for _, v := range exercise.Variations {
+		relations[v.ExerciseID] = v.Relation
+		ids = append(ids, v.ExerciseID)
+	}
+
+	linked := bson.M{"$or": bson.A{
+		bson.M{"_id": bson.M{"$in": ids}},
+		bson.M{"variations.exerciseId": exercise.ID},
+	}}
+	cursor, err := ec.collection.Find(ctx, bson.M{"$and": bson.A{filter, linked}})
+	if err != nil {
+		return nil, err
+	}
+	var found []models.Exercise
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, err
+	}
+
+	results := make([]exerciseVariationResult, 0, len(found))
+	for _, other := range found {
+		relation, ok := relations[other.ID]
+		if !ok {
+			for _, v := range other.Variations {
+				if v.ExerciseID == exercise.ID {
+					relation = models.InverseRelation(v.Relation)
+					break
+				}
+			}
+		}
+		results = append(results, exerciseVariationResult{Relation: relation, Exercise: other})
+	}
+
+	// Easier variations first, then similar, then harder, like a progression chain
+	order := map[string]int{models.VariationEasier: 0, models.VariationSimilar: 1, models.VariationHarder: 2}
+	sort.SliceStable(results, func(i, j int) bool { return order[results[i].Relation] < order[results[j].Relation] })
+	return results, nil
+}
+
+// scoreAlternative rates how well candidate substitutes for exercise, from 0 to 1
+func scoreAlternative(exercise, candidate models.Exercise) exerciseAlternative {
+	alt := exerciseAlternative{Exercise: candidate, SharedMuscles: []string{}}
+
+	muscles := map[string]bool{}
+	for _, m := range exercise.MainMuscles {
+		muscles[strings.ToLower(m)] = true
+	}
+	union := len(muscles)
+	for _, m := range candidate.MainMuscles {
+		if muscles[strings.ToLower(m)] {
+			alt.SharedMuscles = append(alt.SharedMuscles, m)
+		} else {
+			union++
+		}
+	}
+	if union > 0 {
+		alt.Score += muscleOverlapWeight * float64(len(alt.SharedMuscles)) / float64(union)
+	}
+
+	if strings.EqualFold(exercise.Category, candidate.Category) {
+		alt.SameCategory = true
+		alt.Score += categoryWeight
+	}
+
+	from, okFrom := difficultyLevels[strings.ToLower(exercise.Difficulty)]
+	to, okTo := difficultyLevels[strings.ToLower(candidate.Difficulty)]
+	switch {
+	case strings.EqualFold(exercise.Difficulty, candidate.Difficulty):
+		alt.SameDifficulty = true
+		alt.Score += difficultyWeight
+	case okFrom && okTo && (from-to == 1 || to-from == 1):
+		alt.Score += difficultyWeight / 2
+	}
+
+	alt.Score += equipmentWeight * equipmentSimilarity(exercise.Equipment, candidate.Equipment)
+	alt.Score = utils.Round(alt.Score, 3)
+	return alt
+}
+
+// equipmentSimilarity is the Jaccard index of two equipment lists; two bodyweight
+// exercises match fully
+func equipmentSimilarity(a, b []string) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	set := map[string]bool{}
+	for _, item := range a {
+		set[item] = true
+	}
+	shared, union := 0, len(set)
+	for _, item := range b {
+		if set[item] {
+			shared++
+		} else {
+			union++
+		}
+	}
+	return float64(shared) / float64(union)
+}
+
+// variationsRequest replaces the curated variations of an exercise
+type variationsRequest struct {
+	Variations []models.ExerciseVariation `json:"variations" validate:"max=20,dive"`
+}
+
+// UpdateExerciseVariations replaces the curated variation links of a global exercise.
+// Every linked exercise must be another guide in the global catalog.
+func (ec *ExerciseGuideController) UpdateExerciseVariations(c echo.Context) error {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid exercise ID"})
+	}
+
+	var req variationsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid input", "details": err.Error()})
+	}
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err))
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(req.Variations))
+	seen := map[primitive.ObjectID]bool{}
+	for _, v := range req.Variations {
+		if v.ExerciseID == objID {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "An exercise cannot be a variation of itself"})
+		}
+		if seen[v.ExerciseID] {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "Each exercise can be linked only once", "exerciseId": v.ExerciseID})
+		}
+		seen[v.ExerciseID] = true
+		ids = append(ids, v.ExerciseID)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	count, err := ec.collection.CountDocuments(ctx, bson.M{"_id": bson.M{"$in": ids}, "ownerId": nil, "deletedAt": nil})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to check variations"})
+	}
+	if count != int64(len(ids)) {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "Variations must link to existing exercises in the global catalog"})
+	}
+
+	return ec.saveExerciseChange(c, bson.M{"_id": objID, "ownerId": nil}, func(exercise *models.Exercise) {
+		exercise.Variations = req.Variations
+		if len(exercise.Variations) == 0 {
+			exercise.Variations = nil
+		}
+	})
+}
+
+// removeVariationLinks drops the curated links other exercises hold to a deleted exercise
+func (ec *ExerciseGuideController) removeVariationLinks(ctx context.Context, exerciseID primitive.ObjectID) error {
+	_, err := ec.collection.UpdateMany(ctx,
+		bson.M{"variations.exerciseId": exerciseID},
+		bson.M{"$pull": bson.M{"variations": bson.M{"exerciseId": exerciseID}}},
+	)
+	return err
+}
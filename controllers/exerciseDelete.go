@@ -0,0 +1,277 @@
+package controllers
+
+import (
+	"context"
+	"fitness-backend/models"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// DeleteModeBlock refuses to delete an exercise that goals still reference
+	DeleteModeBlock = "block"
+	// DeleteModeSoft hides the exercise from the catalog but keeps it resolvable by ID
+	DeleteModeSoft = "soft"
+	// DeleteModeReassign points referencing goals at another exercise, then deletes
+	DeleteModeReassign = "reassign"
+
+	// maxReportedReferences caps the goals listed in a reference report
+	maxReportedReferences = 100
+)
+
+// exerciseReference is a goal that links to an exercise
+type exerciseReference struct {
+	UserID   primitive.ObjectID `bson:"userId" json:"userId"`
+	Date     time.Time          `bson:"date" json:"date"`
+	GoalID   primitive.ObjectID `bson:"goalId" json:"goalId"`
+	GoalName string             `bson:"goalName" json:"goalName"`
+}
+
+// referenceReport summarizes the goals, workout templates, training programs, recurring goals,
+// personal records and workout sessions affected by deleting an exercise. Goals lists at
+// most maxReportedReferences of them.
+type referenceReport struct {
+	Count     int64               `json:"count"`
+	Goals     []exerciseReference `json:"goals"`
+	Truncated bool                `json:"truncated"`
+	Templates int64               `json:"templates"`
+	Programs  int64               `json:"programs"`
+	Recurring int64               `json:"recurringGoals"`
+	Records   int64               `json:"personalRecords"`
+	Sessions  int64               `json:"sessions"`
+}
+
+// inUse reports whether anything still links to the exercise
+func (r referenceReport) inUse() bool {
+	return r.Count > 0 || r.Templates > 0 || r.Programs > 0 || r.Recurring > 0 || r.Records > 0 || r.Sessions > 0
+}
+
+// deleteExercise removes the exercise matching filter. ?mode= decides what happens when
+// goals still reference it: "block" (default) refuses with 409, "soft" hides it from the
+// catalog, and "reassign" moves the goals to ?target= before deleting.
+func (ec *ExerciseGuideController) deleteExercise(c echo.Context, filter bson.M) error {
+	mode := c.QueryParam("mode")
+	if mode == "" {
+		mode = DeleteModeBlock
+	}
+	if mode != DeleteModeBlock && mode != DeleteModeSoft && mode != DeleteModeReassign {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "mode must be one of block, soft or reassign"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var exercise models.Exercise
+	err := ec.collection.FindOne(ctx, filter).Decode(&exercise)
+	if err == mongo.ErrNoDocuments {
+		return c.JSON(http.StatusNotFound, echo.Map{"error": "Exercise not found"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to fetch exercise"})
+	}
+
+	report, err := ec.exerciseReferences(ctx, exercise.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to check exercise references"})
+	}
+
+	switch mode {
+	case DeleteModeSoft:
+		if exercise.DeletedAt != nil {
+			return c.JSON(http.StatusConflict, echo.Map{"error": "Exercise is already deleted"})
+		}
+		if _, err := ec.collection.UpdateOne(ctx, bson.M{"_id": exercise.ID}, bson.M{"$set": bson.M{"deletedAt": time.Now()}}); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to delete exercise"})
+		}
+		return c.JSON(http.StatusOK, echo.Map{"message": "Exercise hidden from the catalog", "mode": mode, "references": report})
+
+	case DeleteModeReassign:
+		targetID, err := primitive.ObjectIDFromHex(c.QueryParam("target"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "target must be a valid exercise ID"})
+		}
+		if targetID == exercise.ID {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "target must be a different exercise"})
+		}
+
+		// The target has to be visible to everyone who could reference the deleted exercise
+		targetFilter := bson.M{"_id": targetID, "deletedAt": nil, "ownerId": nil}
+		if exercise.OwnerID != nil {
+			targetFilter = bson.M{"$and": bson.A{bson.M{"_id": targetID, "deletedAt": nil}, exerciseVisibility(*exercise.OwnerID)}}
+		}
+		if err := ec.collection.FindOne(ctx, targetFilter).Err(); err == mongo.ErrNoDocuments {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "Target exercise not found"})
+		} else if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to fetch target exercise"})
+		}
+
+		result, err := ec.dailyDataCollection.UpdateMany(ctx,
+			bson.M{"goals.exerciseId": exercise.ID},
+			bson.M{"$set": bson.M{"goals.$[g].exerciseId": targetID}},
+			options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{bson.M{"g.exerciseId": exercise.ID}}}),
+		)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to reassign goals"})
+		}
+		if _, err := ec.templateCollection.UpdateMany(ctx,
+			bson.M{"exercises.exerciseId": exercise.ID},
+			bson.M{"$set": bson.M{"exercises.$[e].exerciseId": targetID}},
+			options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{bson.M{"e.exerciseId": exercise.ID}}}),
+		); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to reassign templates"})
+		}
+		if _, err := ec.programCollection.UpdateMany(ctx,
+			bson.M{"workouts.exercises.exerciseId": exercise.ID},
+			bson.M{"$set": bson.M{"workouts.$[].exercises.$[e].exerciseId": targetID}},
+			options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{bson.M{"e.exerciseId": exercise.ID}}}),
+		); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to reassign programs"})
+		}
+		if _, err := ec.recurringCollection.UpdateMany(ctx,
+			bson.M{"versions.exerciseId": exercise.ID},
+			bson.M{"$set": bson.M{"versions.$[v].exerciseId": targetID}},
+			options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{bson.M{"v.exerciseId": exercise.ID}}}),
+		); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to reassign recurring goals"})
+		}
+		if err := ec.records.reassign(ctx, exercise.ID, targetID); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to reassign personal records"})
+		}
+		if _, err := ec.sessionCollection.UpdateMany(ctx,
+			bson.M{"exercises.exerciseId": exercise.ID},
+			bson.M{"$set": bson.M{"exercises.$[e].exerciseId": targetID}},
+			options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{bson.M{"e.exerciseId": exercise.ID}}}),
+		); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to reassign workout sessions"})
+		}
+		if _, err := ec.collection.DeleteOne(ctx, bson.M{"_id": exercise.ID}); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to delete exercise"})
+		}
+		if err := ec.removeVariationLinks(ctx, exercise.ID); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to remove variation links"})
+		}
+		return c.JSON(http.StatusOK, echo.Map{
+			"message":          "Exercise deleted successfully",
+			"mode":             mode,
+			"references":       report,
+			"reassignedTo":     targetID,
+			"documentsUpdated": result.ModifiedCount,
+		})
+
+	default:
+		if report.inUse() {
+			return c.JSON(http.StatusConflict, echo.Map{
+				"error":      "Exercise is referenced by goals, templates, programs, recurring goals, personal records or sessions; delete with mode=soft or mode=reassign",
+				"mode":       mode,
+				"references": report,
+			})
+		}
+		if _, err := ec.collection.DeleteOne(ctx, bson.M{"_id": exercise.ID}); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to delete exercise"})
+		}
+		if err := ec.removeVariationLinks(ctx, exercise.ID); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to remove variation links"})
+		}
+		return c.JSON(http.StatusOK, echo.Map{"message": "Exercise deleted successfully", "mode": mode, "references": report})
+	}
+}
+
+// exerciseReferences finds the goals in daily_data, the workout templates, the training
+// programs, the recurring goals, the personal records and the workout sessions that link to
+// an exercise
+func (ec *ExerciseGuideController) exerciseReferences(ctx context.Context, exerciseID primitive.ObjectID) (referenceReport, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"goals.exerciseId": exerciseID}}},
+		{{Key: "$unwind", Value: "$goals"}},
+		{{Key: "$match", Value: bson.M{"goals.exerciseId": exerciseID}}},
+		{{Key: "$sort", Value: bson.D{{Key: "date", Value: 1}}}},
+		{{Key: "$facet", Value: bson.M{
+			"total": bson.A{bson.M{"$count": "n"}},
+			"goals": bson.A{
+				bson.M{"$limit": maxReportedReferences},
+				bson.M{"$project": bson.M{
+					"_id":      0,
+					"userId":   1,
+					"date":     1,
+					"goalId":   "$goals._id",
+					"goalName": "$goals.goalName",
+				}},
+			},
+		}}},
+	}
+
+	cursor, err := ec.dailyDataCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return referenceReport{}, err
+	}
+	defer cursor.Close(ctx)
+
+	var facets []struct {
+		Total []struct {
+			N int64 `bson:"n"`
+		} `bson:"total"`
+		Goals []exerciseReference `bson:"goals"`
+	}
+	if err := cursor.All(ctx, &facets); err != nil {
+		return referenceReport{}, err
+	}
+
+	report := referenceReport{Goals: []exerciseReference{}}
+	if len(facets) > 0 {
+		if len(facets[0].Total) > 0 {
+			report.Count = facets[0].Total[0].N
+		}
+		if facets[0].Goals != nil {
+			report.Goals = facets[0].Goals
+		}
+	}
+	report.Truncated = report.Count > int64(len(report.Goals))
+
+	report.Templates, err = ec.templateCollection.CountDocuments(ctx, bson.M{"exercises.exerciseId": exerciseID})
+	if err != nil {
+		return report, err
+	}
+	report.Programs, err = ec.programCollection.CountDocuments(ctx, bson.M{"workouts.exercises.exerciseId": exerciseID})
+	if err != nil {
+		return report, err
+	}
+	report.Recurring, err = ec.recurringCollection.CountDocuments(ctx, bson.M{"versions.exerciseId": exerciseID})
+	if err != nil {
+		return report, err
+	}
+	report.Records, err = ec.records.records.CountDocuments(ctx, bson.M{"exerciseId": exerciseID})
+	if err != nil {
+		return report, err
+	}
+	report.Sessions, err = ec.sessionCollection.CountDocuments(ctx, bson.M{"exercises.exerciseId": exerciseID})
+	return report, err
+}
+
+// RestoreExercise returns a soft-deleted guide to the global catalog
+func (ec *ExerciseGuideController) RestoreExercise(c echo.Context) error {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid exercise ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := ec.collection.UpdateOne(ctx,
+		bson.M{"_id": objID, "ownerId": nil, "deletedAt": bson.M{"$ne": nil}},
+		bson.M{"$unset": bson.M{"deletedAt": ""}},
+	)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to restore exercise"})
+	}
+	if result.MatchedCount == 0 {
+		return c.JSON(http.StatusNotFound, echo.Map{"error": "No deleted exercise with this ID"})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"message": "Exercise restored successfully"})
+}
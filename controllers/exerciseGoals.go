@@ -3,6 +3,7 @@ package controllers
 import (
 	"context"
 	"fitness-backend/models"
+	"fitness-backend/units"
 	"fitness-backend/utils"
 	"fmt"
 	"net/http"
@@ -19,6 +20,9 @@ import (
 type GoalController struct {
 	Collection         *mongo.Collection
 	ExerciseCollection *mongo.Collection // Add this line
+	scheduler          *programScheduler
+	recurring          *recurringScheduler
+	records            *recordKeeper
 }
 
 // Modify NewGoalController
@@ -26,9 +30,21 @@ func NewGoalController(db *mongo.Database) *GoalController {
 	return &GoalController{
 		Collection:         db.Collection("daily_data"),
 		ExerciseCollection: db.Collection("exercise_guides"), // Add this line
+		scheduler:          newProgramScheduler(db),
+		recurring:          newRecurringScheduler(db),
+		records:            newRecordKeeper(db),
 	}
 }
 
+// goalUpdateRules validate partial goal updates, which arrive as untyped maps
+var goalUpdateRules = map[string]string{
+	"goalName":      "required",
+	"goalValue":     "numbertype,gte=0,lte=100000",
+	"progressValue": "numbertype,gte=0,lte=100000",
+	"currentValue":  "numbertype,gte=0,lte=1000",
+	"intensity":     "oneof=light moderate vigorous",
+}
+
 // GetAllGoals retrieves all goals for a specific date
 func (gc *GoalController) GetAllGoals(c echo.Context) error {
 	userIDString, ok := c.Get("user_id").(string)
@@ -41,11 +57,15 @@ func (gc *GoalController) GetAllGoals(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal Server Error"})
 	}
 
-	date, err := time.Parse("2006-01-02", c.Param("date"))
+	date, err := utils.ResolveDate(c, c.Param("date"))
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid date format"))
 	}
 
+	if err := gc.materializeDay(c, userID, date); err != nil {
+		return err
+	}
+
 	filter := bson.M{"userId": userID, "date": date}
 	var dailyData models.DailyDataCollection
 	err = gc.Collection.FindOne(c.Request().Context(), filter).Decode(&dailyData)
@@ -56,7 +76,8 @@ func (gc *GoalController) GetAllGoals(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error retrieving goals"))
 	}
 
-	// Convert goals to proper format
+	// Convert goals to proper format, in the user's units
+	system := utils.UserUnits(c)
 	formattedGoals := make([]map[string]interface{}, 0)
 	for _, goal := range dailyData.Goals {
 		switch g := goal.(type) {
@@ -85,9 +106,9 @@ func (gc *GoalController) GetAllGoals(c echo.Context) error {
 					goalMap[elem.Key] = elem.Value
 				}
 			}
-			formattedGoals = append(formattedGoals, goalMap)
+			formattedGoals = append(formattedGoals, localizeGoal(goalMap, system))
 		case bson.M:
-			formattedGoals = append(formattedGoals, g)
+			formattedGoals = append(formattedGoals, localizeGoal(g, system))
 		default:
 			fmt.Printf("Unknown goal type: %T\n", goal)
 		}
@@ -118,11 +139,15 @@ func (gc *GoalController) GetActiveGoals(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal Server Error"})
 	}
 
-	date, err := time.Parse("2006-01-02", c.Param("date"))
+	date, err := utils.ResolveDate(c, c.Param("date"))
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid date format"))
 	}
 
+	if err := gc.materializeDay(c, userID, date); err != nil {
+		return err
+	}
+
 	filter := bson.M{"userId": userID, "date": date, "goals.isActive": true}
 	var dailyData models.DailyDataCollection
 	err = gc.Collection.FindOne(c.Request().Context(), filter).Decode(&dailyData)
@@ -133,7 +158,15 @@ func (gc *GoalController) GetActiveGoals(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error retrieving active goals"))
 	}
 
-	return c.JSON(http.StatusOK, dailyData.Goals)
+	system := utils.UserUnits(c)
+	goals := make([]interface{}, 0, len(dailyData.Goals))
+	for _, goal := range dailyData.Goals {
+		if goalMap, ok := goalAsMap(goal); ok {
+			goals = append(goals, localizeGoal(goalMap, system))
+		}
+	}
+
+	return c.JSON(http.StatusOK, goals)
 }
 
 // GetGoal retrieves a specific goal by ID for a specific date
@@ -154,10 +187,13 @@ func (gc *GoalController) GetGoal(c echo.Context) error {
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid goal ID format"))
 	}
-	date, err := time.Parse("2006-01-02", c.Param("date"))
+	date, err := utils.ResolveDate(c, c.Param("date"))
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid date format"))
 	}
+	if err := gc.materializeDay(c, userID, date); err != nil {
+		return err
+	}
 
 	filter := bson.M{"userId": userID, "date": date}
 	var dailyData models.DailyDataCollection
@@ -176,6 +212,8 @@ func (gc *GoalController) GetGoal(c echo.Context) error {
 			goalMap := g.Map()
 			goalIDBson, ok := goalMap["_id"].(primitive.ObjectID)
 			if ok && goalIDBson == goalID {
+				localizeGoal(goalMap, utils.UserUnits(c))
+
 				// Try to decode into ExerciseGoal
 				var exerciseGoal models.ExerciseGoal
 				bsonBytes, err := bson.Marshal(goalMap)
@@ -218,17 +256,29 @@ func (gc *GoalController) CreateGoal(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal Server Error"})
 	}
 
-	date, err := time.Parse("2006-01-02", c.Param("date"))
+	date, err := utils.ResolveDate(c, c.Param("date"))
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid date format"))
 	}
 	var request struct {
-		Type string      `json:"type"`
-		Goal interface{} `json:"goal"`
+		Type string      `json:"type" validate:"required,oneof=exercise water calorie customgoal weight"`
+		Goal interface{} `json:"goal" validate:"required"`
 	}
 	if err := c.Bind(&request); err != nil {
 		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid request body"))
 	}
+	if err := c.Validate(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err))
+	}
+	goalData, ok := request.Goal.(map[string]interface{})
+	if !ok {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Goal must be an object"))
+	}
+	system := utils.UserUnits(c)
+	// A recurring goal of the same name is created first, so the request updates it
+	if err := gc.materializeDay(c, userID, date); err != nil {
+		return err
+	}
 
 	// First, check if a goal of this type already exists for the user on this date
 	filter := bson.M{
@@ -236,7 +286,7 @@ func (gc *GoalController) CreateGoal(c echo.Context) error {
 		"date":   date,
 		"goals": bson.M{
 			"$elemMatch": bson.M{
-				"goalName": request.Goal.(map[string]interface{})["goalName"],
+				"goalName": goalData["goalName"],
 			},
 		},
 	}
@@ -250,6 +300,16 @@ func (gc *GoalController) CreateGoal(c echo.Context) error {
 		if err := mapstructure.Decode(request.Goal, &updateData); err != nil {
 			return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid goal data"))
 		}
+		existing := findGoalByName(dailyData.Goals, fmt.Sprint(goalData["goalName"]))
+		if err := checkSetsUntouched(updateData, existing); err != nil {
+			return err
+		}
+		unitKey := goalUnitKey(existing)
+		storedUnit, _ := existing[unitKey].(string)
+		canonicalizeGoalInput(updateData, unitKey, storedUnit, system)
+		if err := utils.ValidateMap(updateData, goalUpdateRules); err != nil {
+			return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err))
+		}
 
 		// Remove fields that shouldn't be updated
 		delete(updateData, "id")
@@ -276,12 +336,28 @@ func (gc *GoalController) CreateGoal(c echo.Context) error {
 			return c.JSON(http.StatusNotFound, utils.ErrorResponse("Goal not found"))
 		}
 
-		return c.JSON(http.StatusOK, updateData)
+		// Respond with the whole updated goal so its values can be shown in the user's units
+		for key, value := range updateData {
+			existing[key] = value
+		}
+		gc.trackGoalRecords(c.Request().Context(), userID, date, existing)
+		return c.JSON(http.StatusOK, localizeGoal(existing, system))
 	} else if err != mongo.ErrNoDocuments {
 		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Database error"))
 	}
 
-	// If no existing goal found, create new one
+	// Sets are logged through the sets endpoints once the goal exists
+	if _, ok := goalData["sets"]; ok {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Use the sets endpoints to log a goal's sets"))
+	}
+
+	// If no existing goal found, create new one, storing its values in canonical units
+	unitKey, defaultUnit := "type", ""
+	if request.Type == "weight" {
+		unitKey, defaultUnit = "unit", units.Kilogram
+	}
+	canonicalizeGoalInput(goalData, unitKey, defaultUnit, system)
+
 	goalID := primitive.NewObjectID()
 	switch request.Type {
 	case "exercise":
@@ -298,6 +374,8 @@ func (gc *GoalController) CreateGoal(c echo.Context) error {
 				return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid exercise ID format"))
 			}
 			goalMap["exerciseId"] = exerciseID
+		} else if goalName, ok := goalMap["goalName"].(string); ok {
+			goalMap["exerciseId"] = gc.getExerciseIDByName(c.Request().Context(), userID, goalName)
 		}
 
 		var goal models.ExerciseGoal
@@ -309,6 +387,9 @@ func (gc *GoalController) CreateGoal(c echo.Context) error {
 		goal.UserID = userID
 		goal.CreatedAt = time.Now()
 		goal.UpdatedAt = time.Now()
+		if err := c.Validate(&goal); err != nil {
+			return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err))
+		}
 		request.Goal = goal
 
 	case "water", "calorie", "customgoal":
@@ -320,6 +401,9 @@ func (gc *GoalController) CreateGoal(c echo.Context) error {
 		goal.UserID = userID
 		goal.CreatedAt = time.Now()
 		goal.UpdatedAt = time.Now()
+		if err := c.Validate(&goal); err != nil {
+			return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err))
+		}
 		request.Goal = goal
 
 	case "weight":
@@ -335,6 +419,9 @@ func (gc *GoalController) CreateGoal(c echo.Context) error {
 		if goal.Entries == nil {
 			goal.Entries = []models.WeightEntry{}
 		}
+		if err := c.Validate(&goal); err != nil {
+			return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err))
+		}
 		request.Goal = goal
 
 	default:
@@ -346,8 +433,11 @@ func (gc *GoalController) CreateGoal(c echo.Context) error {
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to create goal"))
 	}
+	if request.Type == "exercise" {
+		gc.trackGoalRecords(c.Request().Context(), userID, date, request.Goal)
+	}
 
-	return c.JSON(http.StatusCreated, request.Goal)
+	return c.JSON(http.StatusCreated, localizeGoalResponse(request.Goal, system))
 }
 
 // UpdateGoal updates a specific goal by ID for a specific date
@@ -368,7 +458,7 @@ func (gc *GoalController) UpdateGoal(c echo.Context) error {
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid goal ID format"))
 	}
-	date, err := time.Parse("2006-01-02", c.Param("date"))
+	date, err := utils.ResolveDate(c, c.Param("date"))
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid date format"))
 	}
@@ -377,6 +467,9 @@ func (gc *GoalController) UpdateGoal(c echo.Context) error {
 	if err := c.Bind(&updateData); err != nil {
 		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid request body"))
 	}
+	if err := utils.ValidateMap(updateData, goalUpdateRules); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err))
+	}
 
 	// Construct the update document
 	update := bson.M{}
@@ -443,7 +536,7 @@ func (gc *GoalController) DeleteGoal(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid goal ID format"))
 	}
 
-	date, err := time.Parse("2006-01-02", c.Param("date"))
+	date, err := utils.ResolveDate(c, c.Param("date"))
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid date format"))
 	}
@@ -579,7 +672,7 @@ func (gc *GoalController) UpsertGoalByName(c echo.Context) error {
 	}
 
 	// Step 3: Parse parameters
-	date, err := time.Parse("2006-01-02", c.Param("date"))
+	date, err := utils.ResolveDate(c, c.Param("date"))
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid date format"))
 	}
@@ -588,19 +681,23 @@ func (gc *GoalController) UpsertGoalByName(c echo.Context) error {
 	if goalName == "" {
 		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Goal name is required"))
 	}
+	system := utils.UserUnits(c)
+	if err := gc.materializeDay(c, userID, date); err != nil {
+		return err
+	}
 
 	// Step 4: Parse and validate request body
 	var request struct {
-		Type string      `json:"type"`
-		Goal interface{} `json:"goal"`
+		Type string      `json:"type" validate:"required"`
+		Goal interface{} `json:"goal" validate:"required"`
 	}
 
 	if err := c.Bind(&request); err != nil {
 		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid request format"))
 	}
 
-	if request.Type == "" || request.Goal == nil {
-		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Type and goal are required"))
+	if err := c.Validate(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err))
 	}
 
 	// Step 5: Check if goal exists
@@ -621,10 +718,17 @@ func (gc *GoalController) UpsertGoalByName(c echo.Context) error {
 
 		switch request.Type {
 		case "exercise":
-			goal, err = gc.createExerciseGoal(request.Goal, goalID, userID)
+			if goalData, ok := request.Goal.(map[string]interface{}); ok {
+				canonicalizeGoalInput(goalData, "type", "", system)
+			}
+			exerciseGoal, err := gc.createExerciseGoal(request.Goal, goalID, userID)
 			if err != nil {
 				return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
 			}
+			if err := c.Validate(&exerciseGoal); err != nil {
+				return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err))
+			}
+			goal = exerciseGoal
 		default:
 			return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Unsupported goal type"))
 		}
@@ -640,8 +744,9 @@ func (gc *GoalController) UpsertGoalByName(c echo.Context) error {
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to create goal"))
 		}
+		gc.trackGoalRecords(ctx, userID, date, goal)
 
-		return c.JSON(http.StatusCreated, goal)
+		return c.JSON(http.StatusCreated, localizeGoalResponse(goal, system))
 	}
 
 	if err != nil {
@@ -653,6 +758,16 @@ func (gc *GoalController) UpsertGoalByName(c echo.Context) error {
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid goal data"))
 	}
+	existing := findGoalByName(dailyData.Goals, goalName)
+	if err := checkSetsUntouched(goalMap, existing); err != nil {
+		return err
+	}
+	unitKey := goalUnitKey(existing)
+	storedUnit, _ := existing[unitKey].(string)
+	canonicalizeGoalInput(goalMap, unitKey, storedUnit, system)
+	if err := utils.ValidateMap(goalMap, goalUpdateRules); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err))
+	}
 
 	goalMap["updatedAt"] = time.Now()
 
@@ -675,10 +790,23 @@ func (gc *GoalController) UpsertGoalByName(c echo.Context) error {
 		return c.JSON(http.StatusNotFound, utils.ErrorResponse("Goal not found"))
 	}
 
-	return c.JSON(http.StatusOK, goalMap)
+	for key, value := range goalMap {
+		existing[key] = value
+	}
+	gc.trackGoalRecords(ctx, userID, date, existing)
+	return c.JSON(http.StatusOK, localizeGoal(existing, system))
 }
 
 // Helper functions
+
+// materializeDay creates the goals that program workouts and recurring goals schedule on a
+// day the first time it is read or written, returning an *echo.HTTPError on failure
+func (gc *GoalController) materializeDay(c echo.Context, userID primitive.ObjectID, date time.Time) error {
+	if err := materializeDay(c.Request().Context(), gc.scheduler, gc.recurring, userID, date, utils.UserToday(c)); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, utils.ErrorResponse("Error scheduling goals"))
+	}
+	return nil
+}
 func (gc *GoalController) createExerciseGoal(goalData interface{}, goalID, userID primitive.ObjectID) (models.ExerciseGoal, error) {
 	var goalMap map[string]interface{}
 	if err := mapstructure.Decode(goalData, &goalMap); err != nil {
@@ -694,8 +822,8 @@ func (gc *GoalController) createExerciseGoal(goalData interface{}, goalID, userI
 		return models.ExerciseGoal{}, fmt.Errorf("invalid goalName format")
 	}
 
-	// Get exercise ID from exercise_guides collection based on goal name
-	exerciseID := gc.getExerciseIDByName(context.Background(), goalName)
+	// Get exercise ID from the user's private exercises or the catalog based on goal name
+	exerciseID := gc.getExerciseIDByName(context.Background(), userID, goalName)
 
 	// Rest of the validation
 	goalType, ok := goalMap["type"].(string)
@@ -754,9 +882,10 @@ func convertGoalToMap(goalData interface{}) (map[string]interface{}, error) {
 	return goalMap, nil
 }
 
-func (gc *GoalController) getExerciseIDByName(ctx context.Context, name string) primitive.ObjectID {
-	var exercise models.Exercise
-	err := gc.ExerciseCollection.FindOne(ctx, bson.M{"name": name}).Decode(&exercise)
+// getExerciseIDByName links a goal to the exercise of the same name, preferring the user's
+// private exercise over the global catalog
+func (gc *GoalController) getExerciseIDByName(ctx context.Context, userID primitive.ObjectID, name string) primitive.ObjectID {
+	exercise, err := findExerciseForUser(ctx, gc.ExerciseCollection, userID, name)
 	if err != nil {
 		return primitive.NilObjectID
 	}
@@ -3,7 +3,9 @@ package controllers
 import (
 	"context"
 	"fitness-backend/models"
+	"fitness-backend/utils"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -13,69 +15,95 @@ import (
 )
 
 type ExerciseGuideController struct {
-	collection *mongo.Collection
+	collection          *mongo.Collection
+	revisionCollection  *mongo.Collection
+	dailyDataCollection *mongo.Collection
+	userCollection      *mongo.Collection
+	templateCollection  *mongo.Collection
+	programCollection   *mongo.Collection
+	recurringCollection *mongo.Collection
+	sessionCollection   *mongo.Collection
+	records             *recordKeeper
 }
 
 // NewExerciseGuideController initializes a new instance of ExerciseGuideController
 func NewExerciseGuideController(db *mongo.Database) *ExerciseGuideController {
 	return &ExerciseGuideController{
-		collection: db.Collection("exercise_guides"),
+		collection:          db.Collection("exercise_guides"),
+		revisionCollection:  db.Collection("exercise_revisions"),
+		dailyDataCollection: db.Collection("daily_data"),
+		userCollection:      db.Collection("users"),
+		templateCollection:  db.Collection("workout_templates"),
+		programCollection:   db.Collection("training_programs"),
+		recurringCollection: db.Collection("recurring_goals"),
+		sessionCollection:   db.Collection("workout_sessions"),
+		records:             newRecordKeeper(db),
 	}
 }
 
-// GetExercises retrieves all exercise guides from the database
+// GetExercises retrieves a page of exercise guides.
+// Supports ?category=, ?difficulty=, ?muscles=a,b, ?sort=, ?limit= and ?cursor=; the total
+// number of matches is returned in X-Total-Count and the next page cursor in X-Next-Cursor.
+// ?location=home|gym|travel keeps only exercises the user has the equipment for there.
 func (ec *ExerciseGuideController) GetExercises(c echo.Context) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	var exercises []models.Exercise
-	cursor, err := ec.collection.Find(ctx, bson.M{})
+	q, err := parseExerciseQuery(c)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to fetch exercises"})
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
 	}
-	defer cursor.Close(ctx)
-
-	for cursor.Next(ctx) {
-		var exercise models.Exercise
-		if err := cursor.Decode(&exercise); err != nil {
-			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Error decoding exercise"})
-		}
-		exercises = append(exercises, exercise)
+	if err := ec.applyLocation(c, &q); err != nil {
+		return err
 	}
-
-	return c.JSON(http.StatusOK, exercises)
+	return ec.listExercises(c, q, false)
 }
 
-// GetExercisesByCategory retrieves all exercise guides for a specific category
+// GetExercisesByCategory retrieves exercise guides for a specific category
 func (ec *ExerciseGuideController) GetExercisesByCategory(c echo.Context) error {
-	category := c.Param("category")
+	q, err := parseExerciseQuery(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+	q.filter["category"] = c.Param("category")
+	if err := ec.applyLocation(c, &q); err != nil {
+		return err
+	}
+	return ec.listExercises(c, q, true)
+}
 
+// applyLocation narrows a catalog query to the equipment in the user's profile for
+// ?location=, returning an *echo.HTTPError on failure
+func (ec *ExerciseGuideController) applyLocation(c echo.Context, q *exerciseQuery) error {
+	location := c.QueryParam("location")
+	if location == "" {
+		return nil
+	}
+	filter, err := equipmentFilter(c.Request().Context(), ec.userCollection, actorID(c), location)
+	if err != nil {
+		return err
+	}
+	for key, value := range filter {
+		q.filter[key] = value
+	}
+	return nil
+}
+
+// listExercises writes one page of a catalog query, setting the pagination headers
+func (ec *ExerciseGuideController) listExercises(c echo.Context, q exerciseQuery, notFoundWhenEmpty bool) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	var exercises []models.Exercise
-	cursor, err := ec.collection.Find(ctx, bson.M{"category": category})
+	exercises, total, next, err := ec.findExercises(ctx, q)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to fetch exercises"})
 	}
-	defer cursor.Close(ctx)
-
-	for cursor.Next(ctx) {
-		var exercise models.Exercise
-		if err := cursor.Decode(&exercise); err != nil {
-			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Error decoding exercise"})
-		}
-		exercises = append(exercises, exercise)
-	}
-
-	if err := cursor.Err(); err != nil {
-		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Cursor error"})
-	}
 
-	if len(exercises) == 0 {
+	if notFoundWhenEmpty && total == 0 {
 		return c.JSON(http.StatusNotFound, echo.Map{"error": "No exercises found for this category"})
 	}
 
+	c.Response().Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
+	if next != "" {
+		c.Response().Header().Set("X-Next-Cursor", next)
+	}
 	return c.JSON(http.StatusOK, exercises)
 }
 
@@ -90,8 +118,11 @@ func (ec *ExerciseGuideController) GetExerciseByID(c echo.Context) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	// Other users' private exercises are reported as missing
+	filter := bson.M{"$and": bson.A{bson.M{"_id": objID}, exerciseVisibility(actorID(c))}}
+
 	var exercise models.Exercise
-	err = ec.collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&exercise)
+	err = ec.collection.FindOne(ctx, filter).Decode(&exercise)
 	if err == mongo.ErrNoDocuments {
 		return c.JSON(http.StatusNotFound, echo.Map{"error": "Exercise not found"})
 	} else if err != nil {
@@ -110,15 +141,17 @@ func (ec *ExerciseGuideController) CreateExercise(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid input", "details": err.Error()})
 	}
 
-	// Validate required fields
-	if newExercise.Name == "" || newExercise.Category == "" || len(newExercise.MainMuscles) == 0 ||
-		newExercise.Difficulty == "" || len(newExercise.Benefits) == 0 || len(newExercise.Steps) == 0 ||
-		len(newExercise.Tips) == 0 || newExercise.EstimatedTime == "" || newExercise.VideoURL == "" {
-		return c.JSON(http.StatusBadRequest, echo.Map{"error": "All fields are required"})
+	if err := c.Validate(&newExercise); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err))
 	}
 
-	// Assign new ObjectID
+	// Assign new ObjectID; guides created here belong to the global catalog
 	newExercise.ID = primitive.NewObjectID()
+	newExercise.OwnerID = nil
+	// Equipment left out stays unknown, keeping the guide out of location filtering
+	if newExercise.Equipment != nil {
+		newExercise.Equipment = models.NormalizeEquipment(newExercise.Equipment)
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -129,6 +162,10 @@ func (ec *ExerciseGuideController) CreateExercise(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to create exercise", "details": err.Error()})
 	}
 
+	if _, err := ec.recordRevision(ctx, newExercise, models.RevisionCreate, []string{}, actorID(c), 0); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to record revision"})
+	}
+
 	// Return the created exercise
 	return c.JSON(http.StatusCreated, echo.Map{
 		"message":  "Exercise created successfully",
@@ -137,25 +174,13 @@ func (ec *ExerciseGuideController) CreateExercise(c echo.Context) error {
 	})
 }
 
-// DeleteExerciseByID removes an exercise guide by its ID
+// DeleteExerciseByID removes an exercise guide from the global catalog by its ID.
+// See deleteExercise for the ?mode= options used when goals still reference it.
 func (ec *ExerciseGuideController) DeleteExerciseByID(c echo.Context) error {
 	exerciseID := c.Param("id")
 	objID, err := primitive.ObjectIDFromHex(exerciseID)
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid exercise ID"})
 	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	result, err := ec.collection.DeleteOne(ctx, bson.M{"_id": objID})
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to delete exercise"})
-	}
-
-	if result.DeletedCount == 0 {
-		return c.JSON(http.StatusNotFound, echo.Map{"error": "Exercise not found"})
-	}
-
-	return c.JSON(http.StatusOK, echo.Map{"message": "Exercise deleted successfully"})
+	return ec.deleteExercise(c, bson.M{"_id": objID, "ownerId": nil})
 }
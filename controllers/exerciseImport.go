@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"context"
+	"fitness-backend/catalog"
+	"fitness-backend/models"
+	"fitness-backend/utils"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// maxImportSize caps the size of an uploaded catalog file
+const maxImportSize = 5 << 20
+
+// ImportExercises bulk imports exercise guides into the global catalog, updating guides
+// with the same name and creating the rest. The file is sent as the multipart field "file"
+// or as the raw request body. The format comes from ?format=json|csv, the file name or the
+// Content-Type. With ?dryRun=true nothing is written and the report shows what would change.
+func (ec *ExerciseGuideController) ImportExercises(c echo.Context) error {
+	dryRun := false
+	if v := c.QueryParam("dryRun"); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "dryRun must be true or false"})
+		}
+		dryRun = parsed
+	}
+
+	format := c.QueryParam("format")
+	var body io.Reader
+	if file, err := c.FormFile("file"); err == nil {
+		if file.Size > maxImportSize {
+			return c.JSON(http.StatusRequestEntityTooLarge, echo.Map{"error": "File is too large"})
+		}
+		src, err := file.Open()
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "Failed to read file"})
+		}
+		defer src.Close()
+		body = src
+		if format == "" {
+			format = catalog.FormatFromFilename(file.Filename)
+		}
+	} else {
+		body = http.MaxBytesReader(c.Response(), c.Request().Body, maxImportSize)
+		if format == "" {
+			contentType := c.Request().Header.Get(echo.HeaderContentType)
+			switch {
+			case strings.HasPrefix(contentType, echo.MIMEApplicationJSON):
+				format = catalog.FormatJSON
+			case strings.HasPrefix(contentType, "text/csv"):
+				format = catalog.FormatCSV
+			}
+		}
+	}
+	if format == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "Could not tell the file format; pass ?format=json or ?format=csv"})
+	}
+
+	rows, err := catalog.Parse(body, format)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	report, err := ec.Import(ctx, rows, dryRun, actorID(c))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Import failed", "details": err.Error(), "report": report})
+	}
+	return c.JSON(http.StatusOK, report)
+}
+
+// Import upserts exercise rows into the global catalog by name, recording a revision for
+// each created or changed guide. Rows that fail validation are reported and skipped; a
+// database error stops the import and is returned with the report so far.
+func (ec *ExerciseGuideController) Import(ctx context.Context, rows []catalog.Row, dryRun bool, actor primitive.ObjectID) (catalog.Report, error) {
+	report := catalog.Report{DryRun: dryRun, Total: len(rows), Errors: []catalog.RowError{}}
+	seen := map[string]int{}
+
+	for _, row := range rows {
+		if row.Err != nil {
+			report.Fail(row, row.Err)
+			continue
+		}
+
+		exercise := row.Exercise
+		exercise.ID = primitive.NilObjectID
+		exercise.OwnerID = nil
+		exercise.DeletedAt = nil
+		// A file without equipment leaves it unknown rather than marking the exercise bodyweight
+		if exercise.Equipment != nil {
+			exercise.Equipment = models.NormalizeEquipment(exercise.Equipment)
+		}
+		// Variations link by ID, so they are curated in place rather than imported
+		exercise.Variations = nil
+		if err := utils.ValidateStruct(&exercise); err != nil {
+			report.Fail(row, err)
+			continue
+		}
+
+		if line, ok := seen[exercise.Name]; ok {
+			report.Fail(row, fmt.Errorf("duplicate of the exercise on line %d", line))
+			continue
+		}
+		seen[exercise.Name] = row.Line
+
+		var existing models.Exercise
+		err := ec.collection.FindOne(ctx, bson.M{"name": exercise.Name, "ownerId": nil}).Decode(&existing)
+		if err == mongo.ErrNoDocuments {
+			report.Created++
+			if dryRun {
+				continue
+			}
+			exercise.ID = primitive.NewObjectID()
+			if _, err := ec.collection.InsertOne(ctx, exercise); err != nil {
+				return report, err
+			}
+			if _, err := ec.recordRevision(ctx, exercise, models.RevisionCreate, []string{}, actor, 0); err != nil {
+				return report, err
+			}
+			continue
+		} else if err != nil {
+			return report, err
+		}
+
+		// Importing does not undo a soft delete or drop curated variations
+		exercise.ID = existing.ID
+		exercise.DeletedAt = existing.DeletedAt
+		exercise.Variations = existing.Variations
+		// Calorie data the file leaves out, as CSV files always do for intensity METs, is kept
+		if exercise.MET == 0 {
+			exercise.MET = existing.MET
+		}
+		if exercise.IntensityMETs == nil {
+			exercise.IntensityMETs = existing.IntensityMETs
+		}
+		if exercise.Equipment == nil {
+			exercise.Equipment = existing.Equipment
+		}
+		changed := changedExerciseFields(existing, exercise)
+		if len(changed) == 0 {
+			report.Unchanged++
+			continue
+		}
+		report.Updated++
+		if dryRun {
+			continue
+		}
+		if err := ec.ensureBaselineRevision(ctx, existing); err != nil {
+			return report, err
+		}
+		if _, err := ec.collection.ReplaceOne(ctx, bson.M{"_id": existing.ID}, exercise); err != nil {
+			return report, err
+		}
+		if _, err := ec.recordRevision(ctx, exercise, models.RevisionUpdate, changed, actor, 0); err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+// SeedDefaultCatalog imports the bundled catalog when the global catalog is empty, so a
+// fresh deployment is usable. It reports whether anything was seeded.
+func (ec *ExerciseGuideController) SeedDefaultCatalog(ctx context.Context) (catalog.Report, bool, error) {
+	count, err := ec.collection.CountDocuments(ctx, bson.M{"ownerId": nil})
+	if err != nil || count > 0 {
+		return catalog.Report{}, false, err
+	}
+
+	rows, err := catalog.Default()
+	if err != nil {
+		return catalog.Report{}, false, err
+	}
+	report, err := ec.Import(ctx, rows, false, primitive.NilObjectID)
+	return report, err == nil, err
+}
@@ -5,7 +5,6 @@ import (
 	"fitness-backend/utils"
 	"fmt"
 	"net/http"
-	"time"
 
 	"github.com/labstack/echo/v4"
 	"go.mongodb.org/mongo-driver/bson"
@@ -14,16 +13,25 @@ import (
 )
 
 type ProgressController struct {
-	Collection *mongo.Collection
+	Collection         *mongo.Collection
+	UserCollection     *mongo.Collection
+	ExerciseCollection *mongo.Collection
+	scheduler          *programScheduler
+	recurring          *recurringScheduler
 }
 
 func NewProgressController(db *mongo.Database) *ProgressController {
 	return &ProgressController{
-		Collection: db.Collection("daily_data"),
+		Collection:         db.Collection("daily_data"),
+		UserCollection:     db.Collection("users"),
+		ExerciseCollection: db.Collection("exercise_guides"),
+		scheduler:          newProgramScheduler(db),
+		recurring:          newRecurringScheduler(db),
 	}
 }
 
-// GetProgress retrieves the sum of progressValues and goalValues for a specific date
+// GetProgress retrieves the sum of progressValues and goalValues for a specific date,
+// along with the estimated calories burned by each exercise goal and the whole day
 func (pc *ProgressController) GetProgress(c echo.Context) error {
 	userIDString, ok := c.Get("user_id").(string)
 	fmt.Println(userIDString)
@@ -37,11 +45,16 @@ func (pc *ProgressController) GetProgress(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Invalid user ID format"))
 	}
 
-	date, err := time.Parse("2006-01-02", c.Param("date"))
+	date, err := utils.ResolveDate(c, c.Param("date"))
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid date format"))
 	}
 
+	// Program workouts and recurring goals for the day become goals the first time it is read
+	if err := materializeDay(c.Request().Context(), pc.scheduler, pc.recurring, userID, date, utils.UserToday(c)); err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error scheduling goals"))
+	}
+
 	filter := bson.M{"userId": userID, "date": date}
 	var dailyData models.DailyDataCollection
 	err = pc.Collection.FindOne(c.Request().Context(), filter).Decode(&dailyData)
@@ -81,7 +94,21 @@ func (pc *ProgressController) GetProgress(c echo.Context) error {
 		}
 	}
 
-	return c.JSON(http.StatusOK, bson.M{"totalProgress": totalProgress, "totalGoal": totalGoal})
+	burn, err := pc.estimateCaloriesBurned(c.Request().Context(), userID, dailyData.Goals)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error estimating calories burned"))
+	}
+
+	response := bson.M{
+		"totalProgress":  totalProgress,
+		"totalGoal":      totalGoal,
+		"caloriesBurned": burn.Total,
+		"exercises":      burn.Goals,
+	}
+	if len(burn.Missing) > 0 {
+		response["missing"] = burn.Missing
+	}
+	return c.JSON(http.StatusOK, response)
 }
 
 // DeleteProgress sets progressValue to 0 and deletes goal if both values are 0
@@ -101,7 +128,7 @@ func (pc *ProgressController) DeleteProgress(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid goal ID format"))
 	}
 
-	date, err := time.Parse("2006-01-02", c.Param("date"))
+	date, err := utils.ResolveDate(c, c.Param("date"))
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid date format"))
 	}
@@ -109,7 +136,8 @@ func (pc *ProgressController) DeleteProgress(c echo.Context) error {
 	filter := bson.M{"userId": userID, "date": date, "goals._id": bson.M{
 		"$in": []interface{}{goalID, goalID.Hex()},
 	}}
-	update := bson.M{"$set": bson.M{"goals.$.progressValue": 0}}
+	// Progress logged as sets is cleared with them
+	update := bson.M{"$set": bson.M{"goals.$.progressValue": 0}, "$unset": bson.M{"goals.$.sets": "", "goals.$.manualProgress": ""}}
 
 	result, err := pc.Collection.UpdateOne(c.Request().Context(), filter, update)
 	if err != nil {
@@ -0,0 +1,241 @@
+package controllers
+
+import (
+	"context"
+	"fitness-backend/models"
+	"fitness-backend/units"
+	"fitness-backend/utils"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// defaultProgressionWindow is how many past workouts a suggestion looks at by default
+	defaultProgressionWindow = 10
+	// maxProgressionWindow caps ?window=
+	maxProgressionWindow = 50
+)
+
+// defaultIncrements is the linear increment per goal type, in canonical units
+var defaultIncrements = map[string]float64{
+	"reps": 1,
+	"mins": 1,
+	"kms":  0.5,
+}
+
+// defaultLoadIncrements is the load increment per unit system, in that system's unit
+var defaultLoadIncrements = map[string]float64{
+	units.Metric:   2.5,
+	units.Imperial: 5,
+}
+
+// GetNextTarget suggests the next goal value and load for an exercise from the user's past
+// goals on it. The rule is configured by query: ?rule=linear|double, ?increment=,
+// ?loadIncrement= (in the user's weight unit), ?minReps=, ?maxReps=, ?deloadAfter= (0
+// disables deloads), ?deloadPercent= and ?window= (past workouts considered).
+func (ec *ExerciseGuideController) GetNextTarget(c echo.Context) error {
+	userID := actorID(c)
+	if userID.IsZero() {
+		return c.JSON(http.StatusUnauthorized, echo.Map{"error": "Unauthorized"})
+	}
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid exercise ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := ec.collection.FindOne(ctx, bson.M{"$and": bson.A{bson.M{"_id": objID}, exerciseVisibility(userID)}}).Err(); err == mongo.ErrNoDocuments {
+		return c.JSON(http.StatusNotFound, echo.Map{"error": "Exercise not found"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to fetch exercise"})
+	}
+
+	window, err := intParam(c, "window", defaultProgressionWindow, 1, maxProgressionWindow)
+	if err != nil {
+		return err
+	}
+	today := utils.UserToday(c)
+	goals, err := ec.pastGoals(ctx, userID, objID, today, window)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to fetch past goals"})
+	}
+
+	goalType := "reps"
+	if len(goals) > 0 {
+		goalType = goals[len(goals)-1].Goal.Type
+	}
+	system := utils.UserUnits(c)
+	rule, err := progressionRule(c, goalType, system)
+	if err != nil {
+		return err
+	}
+
+	history := make([]utils.ProgressionAttempt, 0, len(goals))
+	for _, past := range goals {
+		// Targets in another unit cannot be compared, and today's goal only counts once met
+		if past.Goal.Type != goalType || (past.Date.Equal(today) && past.Goal.ProgressValue < past.Goal.GoalValue) {
+			continue
+		}
+		history = append(history, progressionAttempt(past.Date, past.Goal))
+	}
+	suggestion := utils.SuggestNextTarget(history, rule)
+
+	// Loads and distances are shown in the user's units
+	displayType := goalType
+	toDisplay := func(value float64, unit string) float64 {
+		display, _ := units.ToDisplay(value, unit, system)
+		return utils.Round(display, 2)
+	}
+	if units.IsConvertible(goalType) {
+		_, displayType = units.ToDisplay(0, goalType, system)
+		suggestion.GoalValue = toDisplay(suggestion.GoalValue, goalType)
+	}
+	suggestion.Load = toDisplay(suggestion.Load, units.Kilogram)
+	for i := range history {
+		if units.IsConvertible(goalType) {
+			history[i].Target = toDisplay(history[i].Target, goalType)
+			history[i].Achieved = toDisplay(history[i].Achieved, goalType)
+		}
+		history[i].Load = toDisplay(history[i].Load, units.Kilogram)
+	}
+	rule.LoadIncrement = toDisplay(rule.LoadIncrement, units.Kilogram)
+	if units.IsConvertible(goalType) {
+		rule.Increment = toDisplay(rule.Increment, goalType)
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"exerciseId": objID,
+		"type":       displayType,
+		"loadUnit":   units.DisplayUnit(units.Kilogram, system),
+		"rule":       rule,
+		"suggestion": suggestion,
+		"history":    history,
+	})
+}
+
+// pastGoal is a goal linked to an exercise with the date it was set for
+type pastGoal struct {
+	Date time.Time           `bson:"date"`
+	Goal models.ExerciseGoal `bson:"goal"`
+}
+
+// pastGoals returns the user's most recent goals on an exercise up to today, oldest first
+func (ec *ExerciseGuideController) pastGoals(ctx context.Context, userID, exerciseID primitive.ObjectID, today time.Time, limit int) ([]pastGoal, error) {
+	pipeline := bson.A{
+		bson.M{"$match": bson.M{"userId": userID, "date": bson.M{"$lte": today}, "goals.exerciseId": exerciseID}},
+		bson.M{"$unwind": "$goals"},
+		bson.M{"$match": bson.M{"goals.exerciseId": exerciseID}},
+		bson.M{"$sort": bson.D{{Key: "date", Value: -1}}},
+		bson.M{"$limit": limit},
+		bson.M{"$project": bson.M{"_id": 0, "date": 1, "goal": "$goals"}},
+	}
+	cursor, err := ec.dailyDataCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var goals []pastGoal
+	if err := cursor.All(ctx, &goals); err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(goals)-1; i < j; i, j = i+1, j-1 {
+		goals[i], goals[j] = goals[j], goals[i]
+	}
+	return goals, nil
+}
+
+// progressionAttempt summarizes a past goal; with weighted sets, the heaviest weight is the
+// working load and its weakest set the reps achieved at it
+func progressionAttempt(date time.Time, goal models.ExerciseGoal) utils.ProgressionAttempt {
+	attempt := utils.ProgressionAttempt{Date: date, Target: goal.GoalValue, Achieved: goal.ProgressValue}
+	for _, set := range goal.Sets {
+		switch {
+		case set.Weight > attempt.Load:
+			attempt.Load, attempt.LoadSets, attempt.LoadReps = set.Weight, 1, set.Reps
+		case set.Weight == attempt.Load && set.Weight > 0:
+			attempt.LoadSets++
+			if set.Reps < attempt.LoadReps {
+				attempt.LoadReps = set.Reps
+			}
+		}
+	}
+	return attempt
+}
+
+// progressionRule reads the progression rule from the query, converting increments from the
+// user's units, and returns an *echo.HTTPError for invalid values
+func progressionRule(c echo.Context, goalType, system string) (utils.ProgressionRule, error) {
+	rule := utils.ProgressionRule{Rule: c.QueryParam("rule")}
+	if rule.Rule == "" {
+		rule.Rule = utils.ProgressionLinear
+	}
+	if rule.Rule != utils.ProgressionLinear && rule.Rule != utils.ProgressionDouble {
+		return rule, echo.NewHTTPError(http.StatusBadRequest, echo.Map{"error": "rule must be linear or double"})
+	}
+
+	increment, _ := units.ToDisplay(defaultIncrements[goalType], goalType, system)
+	var err error
+	if rule.Increment, err = floatParam(c, "increment", increment, 0, 10000); err != nil {
+		return rule, err
+	}
+	if rule.LoadIncrement, err = floatParam(c, "loadIncrement", defaultLoadIncrements[system], 0, 100); err != nil {
+		return rule, err
+	}
+	if units.IsConvertible(goalType) {
+		rule.Increment = units.Convert(rule.Increment, units.DisplayUnit(goalType, system), goalType)
+	}
+	rule.LoadIncrement = units.Convert(rule.LoadIncrement, units.DisplayUnit(units.Kilogram, system), units.Kilogram)
+
+	if rule.MinReps, err = intParam(c, "minReps", 8, 1, 100); err != nil {
+		return rule, err
+	}
+	maxReps := 12
+	if rule.MinReps > maxReps {
+		maxReps = rule.MinReps
+	}
+	if rule.MaxReps, err = intParam(c, "maxReps", maxReps, rule.MinReps, 100); err != nil {
+		return rule, err
+	}
+	if rule.DeloadAfter, err = intParam(c, "deloadAfter", 3, 0, 20); err != nil {
+		return rule, err
+	}
+	if rule.DeloadPercent, err = floatParam(c, "deloadPercent", 10, 1, 50); err != nil {
+		return rule, err
+	}
+	return rule, nil
+}
+
+// floatParam reads a numeric query parameter within [min, max], returning an
+// *echo.HTTPError when it is invalid
+func floatParam(c echo.Context, name string, def, min, max float64) (float64, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < min || n > max {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, echo.Map{"error": "Invalid " + name, "min": min, "max": max})
+	}
+	return n, nil
+}
+
+// intParam reads an integer query parameter within [min, max], returning an
+// *echo.HTTPError when it is invalid
+func intParam(c echo.Context, name string, def, min, max int) (int, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min || n > max {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, echo.Map{"error": "Invalid " + name, "min": min, "max": max})
+	}
+	return n, nil
+}
@@ -0,0 +1,211 @@
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fitness-backend/models"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	defaultExercisePageSize = 20
+	maxExercisePageSize     = 100
+)
+
+// exerciseSortFields maps the ?sort= values to document fields; prefix with "-" for descending
+var exerciseSortFields = map[string]string{
+	"name":       "name",
+	"category":   "category",
+	"difficulty": "difficulty",
+}
+
+// exerciseQuery is a parsed catalog listing request
+type exerciseQuery struct {
+	filter    bson.M
+	sortField string // "_id" when no sort was requested
+	sortDesc  bool
+	limit     int64
+	after     *exerciseCursor
+}
+
+// exerciseCursor marks the last exercise of a page. It is handed to clients as an opaque string.
+type exerciseCursor struct {
+	Value interface{}        `json:"v,omitempty"` // Sort field value of the last exercise
+	ID    primitive.ObjectID `json:"id"`
+}
+
+func (cur exerciseCursor) encode() string {
+	b, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeExerciseCursor(s string) (*exerciseCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	var cur exerciseCursor
+	if err := json.Unmarshal(b, &cur); err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	return &cur, nil
+}
+
+// exerciseVisibility matches the global catalog plus the private exercises owned by userID
+func exerciseVisibility(userID primitive.ObjectID) bson.M {
+	return bson.M{"$or": bson.A{
+		bson.M{"ownerId": nil},
+		bson.M{"ownerId": userID},
+	}}
+}
+
+// exerciseScopeFilter narrows a listing by ?scope=: "all" (default) is the global catalog
+// merged with the user's private exercises, "global" and "mine" return only one of them
+func exerciseScopeFilter(scope string, userID primitive.ObjectID) (bson.M, error) {
+	switch scope {
+	case "", "all":
+		return exerciseVisibility(userID), nil
+	case "global":
+		return bson.M{"ownerId": nil}, nil
+	case "mine":
+		return bson.M{"ownerId": userID}, nil
+	default:
+		return nil, fmt.Errorf("scope must be one of all, global or mine")
+	}
+}
+
+// parseExerciseQuery reads the filter, sort and pagination query parameters:
+// scope, category, difficulty, muscles (comma separated, any match), sort, limit and cursor
+func parseExerciseQuery(c echo.Context) (exerciseQuery, error) {
+	q := exerciseQuery{filter: bson.M{}, sortField: "_id", limit: defaultExercisePageSize}
+
+	scope, err := exerciseScopeFilter(c.QueryParam("scope"), actorID(c))
+	if err != nil {
+		return q, err
+	}
+	for key, value := range scope {
+		q.filter[key] = value
+	}
+	// Soft-deleted guides stay resolvable by ID but are left out of listings
+	q.filter["deletedAt"] = nil
+
+	if category := c.QueryParam("category"); category != "" {
+		q.filter["category"] = category
+	}
+	if difficulty := c.QueryParam("difficulty"); difficulty != "" {
+		q.filter["difficulty"] = difficulty
+	}
+	if muscles := c.QueryParam("muscles"); muscles != "" {
+		var list []string
+		for _, m := range strings.Split(muscles, ",") {
+			if m = strings.TrimSpace(m); m != "" {
+				list = append(list, m)
+			}
+		}
+		if len(list) > 0 {
+			q.filter["mainMuscles"] = bson.M{"$in": list}
+		}
+	}
+
+	if sort := c.QueryParam("sort"); sort != "" {
+		field, ok := exerciseSortFields[strings.TrimPrefix(sort, "-")]
+		if !ok {
+			return q, fmt.Errorf("sort must be one of name, category or difficulty, optionally prefixed with -")
+		}
+		q.sortField = field
+		q.sortDesc = strings.HasPrefix(sort, "-")
+	}
+
+	if limit := c.QueryParam("limit"); limit != "" {
+		n, err := strconv.ParseInt(limit, 10, 64)
+		if err != nil || n < 1 || n > maxExercisePageSize {
+			return q, fmt.Errorf("limit must be between 1 and %d", maxExercisePageSize)
+		}
+		q.limit = n
+	}
+
+	if cursor := c.QueryParam("cursor"); cursor != "" {
+		after, err := decodeExerciseCursor(cursor)
+		if err != nil {
+			return q, err
+		}
+		q.after = after
+	}
+
+	return q, nil
+}
+
+// findExercises runs a catalog query and returns one page, the total number of matches
+// and the cursor for the next page ("" on the last page)
+func (ec *ExerciseGuideController) findExercises(ctx context.Context, q exerciseQuery) ([]models.Exercise, int64, string, error) {
+	total, err := ec.collection.CountDocuments(ctx, q.filter)
+	if err != nil {
+		return nil, 0, "", err
+	}
+
+	direction := 1
+	cmp := "$gt"
+	if q.sortDesc {
+		direction = -1
+		cmp = "$lt"
+	}
+
+	filter := q.filter
+	if q.after != nil {
+		var page bson.M
+		if q.sortField == "_id" {
+			page = bson.M{"_id": bson.M{cmp: q.after.ID}}
+		} else {
+			page = bson.M{"$or": bson.A{
+				bson.M{q.sortField: bson.M{cmp: q.after.Value}},
+				bson.M{q.sortField: q.after.Value, "_id": bson.M{cmp: q.after.ID}},
+			}}
+		}
+		filter = bson.M{"$and": bson.A{q.filter, page}}
+	}
+
+	sort := bson.D{{Key: "_id", Value: direction}}
+	if q.sortField != "_id" {
+		sort = bson.D{{Key: q.sortField, Value: direction}, {Key: "_id", Value: direction}}
+	}
+
+	// Fetch one extra exercise to learn whether there is another page
+	opts := options.Find().SetSort(sort).SetLimit(q.limit + 1)
+	cursor, err := ec.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	defer cursor.Close(ctx)
+
+	exercises := make([]models.Exercise, 0, q.limit)
+	if err := cursor.All(ctx, &exercises); err != nil {
+		return nil, 0, "", err
+	}
+
+	next := ""
+	if int64(len(exercises)) > q.limit {
+		exercises = exercises[:q.limit]
+		last := exercises[len(exercises)-1]
+		cur := exerciseCursor{ID: last.ID}
+		switch q.sortField {
+		case "name":
+			cur.Value = last.Name
+		case "category":
+			cur.Value = last.Category
+		case "difficulty":
+			cur.Value = last.Difficulty
+		}
+		next = cur.encode()
+	}
+
+	return exercises, total, next, nil
+}
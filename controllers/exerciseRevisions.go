@@ -0,0 +1,354 @@
+package controllers
+
+import (
+	"context"
+	"fitness-backend/models"
+	"fitness-backend/utils"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// exercisePatchRequest lists the fields a PATCH may change; nil fields are left untouched
+type exercisePatchRequest struct {
+	Name          *string               `json:"name" validate:"omitnil,min=1"`
+	Category      *string               `json:"category" validate:"omitnil,min=1"`
+	MainMuscles   *[]string             `json:"mainMuscles" validate:"omitnil,min=1,dive,required"`
+	Difficulty    *string               `json:"difficulty" validate:"omitnil,min=1"`
+	Benefits      *[]string             `json:"benefits" validate:"omitnil,min=1,dive,required"`
+	Steps         *[]string             `json:"steps" validate:"omitnil,min=1,dive,required"`
+	Tips          *[]string             `json:"tips" validate:"omitnil,min=1,dive,required"`
+	EstimatedTime *models.DurationRange `json:"estimatedTime" validate:"omitnil"`
+	Equipment     *[]string             `json:"equipment" validate:"omitnil,dive,required"`
+	VideoURL      *string               `json:"videoUrl" validate:"omitnil,url"`
+	MET           *float64              `json:"met" validate:"omitnil,gt=0,lte=25"`
+	IntensityMETs *map[string]float64   `json:"intensityMets" validate:"omitnil,dive,keys,oneof=light moderate vigorous,endkeys,gt=0,lte=25"`
+}
+
+// apply copies the fields present in the patch onto an exercise
+func (p exercisePatchRequest) apply(exercise *models.Exercise) {
+	if p.Name != nil {
+		exercise.Name = *p.Name
+	}
+	if p.Category != nil {
+		exercise.Category = *p.Category
+	}
+	if p.MainMuscles != nil {
+		exercise.MainMuscles = *p.MainMuscles
+	}
+	if p.Difficulty != nil {
+		exercise.Difficulty = *p.Difficulty
+	}
+	if p.Benefits != nil {
+		exercise.Benefits = *p.Benefits
+	}
+	if p.Steps != nil {
+		exercise.Steps = *p.Steps
+	}
+	if p.Tips != nil {
+		exercise.Tips = *p.Tips
+	}
+	if p.EstimatedTime != nil {
+		exercise.EstimatedTime = *p.EstimatedTime
+	}
+	if p.Equipment != nil {
+		exercise.Equipment = models.NormalizeEquipment(*p.Equipment)
+	}
+	if p.VideoURL != nil {
+		exercise.VideoURL = *p.VideoURL
+	}
+	if p.MET != nil {
+		exercise.MET = *p.MET
+	}
+	if p.IntensityMETs != nil {
+		exercise.IntensityMETs = *p.IntensityMETs
+	}
+}
+
+// ReplaceExercise replaces every field of an exercise guide (PUT)
+func (ec *ExerciseGuideController) ReplaceExercise(c echo.Context) error {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid exercise ID"})
+	}
+
+	var replacement models.Exercise
+	if err := c.Bind(&replacement); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid input", "details": err.Error()})
+	}
+	if err := c.Validate(&replacement); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err))
+	}
+
+	return ec.saveExerciseChange(c, bson.M{"_id": objID, "ownerId": nil}, func(current *models.Exercise) {
+		replacement.ID = current.ID
+		replacement.OwnerID = current.OwnerID
+		replacement.DeletedAt = current.DeletedAt
+		// Variations are edited through their own endpoint
+		replacement.Variations = current.Variations
+		// Clients that predate equipment leave it out, which must not make the exercise bodyweight
+		if replacement.Equipment == nil {
+			replacement.Equipment = current.Equipment
+		} else {
+			replacement.Equipment = models.NormalizeEquipment(replacement.Equipment)
+		}
+		*current = replacement
+	})
+}
+
+// PatchExercise updates the fields present in the request body (PATCH)
+func (ec *ExerciseGuideController) PatchExercise(c echo.Context) error {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid exercise ID"})
+	}
+
+	var patch exercisePatchRequest
+	if err := c.Bind(&patch); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid input", "details": err.Error()})
+	}
+	if err := c.Validate(&patch); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err))
+	}
+
+	return ec.saveExerciseChange(c, bson.M{"_id": objID, "ownerId": nil}, patch.apply)
+}
+
+// saveExerciseChange loads the exercise matching filter, applies change, stores the result
+// and records a revision
+func (ec *ExerciseGuideController) saveExerciseChange(c echo.Context, filter bson.M, change func(*models.Exercise)) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var current models.Exercise
+	err := ec.collection.FindOne(ctx, filter).Decode(&current)
+	if err == mongo.ErrNoDocuments {
+		return c.JSON(http.StatusNotFound, echo.Map{"error": "Exercise not found"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to fetch exercise"})
+	}
+
+	updated := current
+	change(&updated)
+
+	changed := changedExerciseFields(current, updated)
+	if len(changed) == 0 {
+		return c.JSON(http.StatusOK, echo.Map{"message": "No changes", "exercise": current})
+	}
+
+	if err := ec.ensureBaselineRevision(ctx, current); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to record revision"})
+	}
+
+	if _, err := ec.collection.ReplaceOne(ctx, bson.M{"_id": current.ID}, updated); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to update exercise"})
+	}
+
+	revision, err := ec.recordRevision(ctx, updated, models.RevisionUpdate, changed, actorID(c), 0)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to record revision"})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"message":  "Exercise updated successfully",
+		"exercise": updated,
+		"revision": revision.Revision,
+	})
+}
+
+// GetExerciseRevisions lists the revisions of an exercise guide, newest first
+func (ec *ExerciseGuideController) GetExerciseRevisions(c echo.Context) error {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid exercise ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "revision", Value: -1}})
+	cursor, err := ec.revisionCollection.Find(ctx, bson.M{"exerciseId": objID}, opts)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to fetch revisions"})
+	}
+	defer cursor.Close(ctx)
+
+	revisions := make([]models.ExerciseRevision, 0)
+	if err := cursor.All(ctx, &revisions); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Error decoding revisions"})
+	}
+
+	return c.JSON(http.StatusOK, revisions)
+}
+
+// GetExerciseRevision returns a single revision of an exercise guide
+func (ec *ExerciseGuideController) GetExerciseRevision(c echo.Context) error {
+	revision, err := ec.findRevision(c)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, revision)
+}
+
+// RollbackExercise restores an exercise guide to an earlier revision. The rollback is
+// itself recorded as a new revision so it can be undone.
+func (ec *ExerciseGuideController) RollbackExercise(c echo.Context) error {
+	target, err := ec.findRevision(c)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var current models.Exercise
+	err = ec.collection.FindOne(ctx, bson.M{"_id": target.ExerciseID}).Decode(&current)
+	if err == mongo.ErrNoDocuments {
+		return c.JSON(http.StatusNotFound, echo.Map{"error": "Exercise not found"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to fetch exercise"})
+	}
+
+	restored := target.Snapshot
+	// Ownership and deletion are not part of a guide's content
+	restored.ID = current.ID
+	restored.OwnerID = current.OwnerID
+	restored.DeletedAt = current.DeletedAt
+	// Variations are curated through their own endpoint and may link to exercises deleted
+	// since the snapshot, so the current ones stay
+	restored.Variations = current.Variations
+	// Snapshots taken before equipment existed have none; that means unknown, not bodyweight
+	if restored.Equipment == nil {
+		restored.Equipment = current.Equipment
+	}
+	changed := changedExerciseFields(current, restored)
+	if len(changed) == 0 {
+		return c.JSON(http.StatusOK, echo.Map{"message": "Exercise already matches this revision", "exercise": current})
+	}
+
+	if _, err := ec.collection.ReplaceOne(ctx, bson.M{"_id": current.ID}, restored); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to restore exercise"})
+	}
+
+	revision, err := ec.recordRevision(ctx, restored, models.RevisionRollback, changed, actorID(c), target.Revision)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to record revision"})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"message":  "Exercise restored successfully",
+		"exercise": restored,
+		"revision": revision.Revision,
+	})
+}
+
+// findRevision loads the revision named by the :id and :revision path parameters,
+// returning an *echo.HTTPError on failure
+func (ec *ExerciseGuideController) findRevision(c echo.Context) (models.ExerciseRevision, error) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return models.ExerciseRevision{}, echo.NewHTTPError(http.StatusBadRequest, utils.ErrorResponse("Invalid exercise ID"))
+	}
+	number, err := strconv.Atoi(c.Param("revision"))
+	if err != nil || number < 1 {
+		return models.ExerciseRevision{}, echo.NewHTTPError(http.StatusBadRequest, utils.ErrorResponse("Invalid revision number"))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var revision models.ExerciseRevision
+	err = ec.revisionCollection.FindOne(ctx, bson.M{"exerciseId": objID, "revision": number}).Decode(&revision)
+	if err == mongo.ErrNoDocuments {
+		return revision, echo.NewHTTPError(http.StatusNotFound, utils.ErrorResponse("Revision not found"))
+	} else if err != nil {
+		return revision, echo.NewHTTPError(http.StatusInternalServerError, utils.ErrorResponse("Failed to fetch revision"))
+	}
+	return revision, nil
+}
+
+// ensureBaselineRevision snapshots a guide created before revisions were recorded,
+// so its original content can still be restored
+func (ec *ExerciseGuideController) ensureBaselineRevision(ctx context.Context, exercise models.Exercise) error {
+	count, err := ec.revisionCollection.CountDocuments(ctx, bson.M{"exerciseId": exercise.ID})
+	if err != nil || count > 0 {
+		return err
+	}
+	_, err = ec.recordRevision(ctx, exercise, models.RevisionBaseline, []string{}, primitive.NilObjectID, 0)
+	return err
+}
+
+// recordRevisionAttempts bounds the retries when concurrent writes to an exercise take the
+// same revision number
+const recordRevisionAttempts = 5
+
+// recordRevision stores a snapshot of an exercise as its next revision. The number is read
+// from the latest revision, so a concurrent write can take it first; the unique index on
+// exerciseId and revision rejects the duplicate and the next number is tried.
+func (ec *ExerciseGuideController) recordRevision(ctx context.Context, exercise models.Exercise, action string, changed []string, changedBy primitive.ObjectID, rolledBackFrom int) (models.ExerciseRevision, error) {
+	revision := models.ExerciseRevision{
+		ExerciseID:     exercise.ID,
+		Action:         action,
+		ChangedFields:  changed,
+		ChangedBy:      changedBy,
+		ChangedAt:      time.Now(),
+		RolledBackFrom: rolledBackFrom,
+		Snapshot:       exercise,
+	}
+
+	var err error
+	for attempt := 0; attempt < recordRevisionAttempts; attempt++ {
+		next := 1
+		var latest models.ExerciseRevision
+		err = ec.revisionCollection.FindOne(ctx,
+			bson.M{"exerciseId": exercise.ID},
+			options.FindOne().SetSort(bson.D{{Key: "revision", Value: -1}}),
+		).Decode(&latest)
+		if err == nil {
+			next = latest.Revision + 1
+		} else if err != mongo.ErrNoDocuments {
+			return models.ExerciseRevision{}, err
+		}
+
+		revision.ID = primitive.NewObjectID()
+		revision.Revision = next
+		if _, err = ec.revisionCollection.InsertOne(ctx, revision); !mongo.IsDuplicateKeyError(err) {
+			return revision, err
+		}
+	}
+	return revision, err
+}
+
+// changedExerciseFields returns the JSON names of the fields that differ between two exercises
+func changedExerciseFields(before, after models.Exercise) []string {
+	changed := []string{}
+	b, a := reflect.ValueOf(before), reflect.ValueOf(after)
+	for i := 0; i < b.NumField(); i++ {
+		field := b.Type().Field(i)
+		if field.Name == "ID" {
+			continue
+		}
+		if !reflect.DeepEqual(b.Field(i).Interface(), a.Field(i).Interface()) {
+			changed = append(changed, strings.SplitN(field.Tag.Get("json"), ",", 2)[0])
+		}
+	}
+	return changed
+}
+
+// actorID returns the authenticated user's ID, or NilObjectID when it is missing
+func actorID(c echo.Context) primitive.ObjectID {
+	userIDString, _ := c.Get("user_id").(string)
+	id, err := primitive.ObjectIDFromHex(userIDString)
+	if err != nil {
+		return primitive.NilObjectID
+	}
+	return id
+}
@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"context"
+	"fitness-backend/models"
+	"fitness-backend/utils"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	defaultSearchLimit = 20
+	// fuzzyMatchThreshold is the minimum average token similarity for a fuzzy match
+	fuzzyMatchThreshold = 0.7
+)
+
+// exerciseSearchResult is an exercise with its relevance score
+type exerciseSearchResult struct {
+	models.Exercise
+	Score float64 `json:"score"`
+}
+
+// SearchExercises finds exercise guides matching ?q= using the text index, falling back
+// to typo-tolerant matching on names and muscles when the text search finds nothing
+func (ec *ExerciseGuideController) SearchExercises(c echo.Context) error {
+	query := strings.TrimSpace(c.QueryParam("q"))
+	if query == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "q is required"})
+	}
+
+	limit := int64(defaultSearchLimit)
+	if l := c.QueryParam("limit"); l != "" {
+		n, err := strconv.ParseInt(l, 10, 64)
+		if err != nil || n < 1 || n > maxExercisePageSize {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid limit"})
+		}
+		limit = n
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	visible := exerciseVisibility(actorID(c))
+	visible["deletedAt"] = nil
+	results, err := ec.textSearch(ctx, query, visible, limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to search exercises"})
+	}
+	mode := "text"
+
+	if len(results) == 0 {
+		results, err = ec.fuzzySearch(ctx, query, visible, limit)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to search exercises"})
+		}
+		mode = "fuzzy"
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"query": query, "mode": mode, "results": results})
+}
+
+// textSearch queries the text index among the exercises matching filter, ranking by the
+// index's relevance score
+func (ec *ExerciseGuideController) textSearch(ctx context.Context, query string, filter bson.M, limit int64) ([]exerciseSearchResult, error) {
+	score := bson.M{"$meta": "textScore"}
+	opts := options.Find().
+		SetProjection(bson.M{"score": score}).
+		SetSort(bson.D{{Key: "score", Value: score}}).
+		SetLimit(limit)
+
+	search := bson.M{"$and": bson.A{bson.M{"$text": bson.M{"$search": query}}, filter}}
+	cursor, err := ec.collection.Find(ctx, search, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	results := make([]exerciseSearchResult, 0)
+	for cursor.Next(ctx) {
+		var result struct {
+			models.Exercise `bson:",inline"`
+			Score           float64 `bson:"score"`
+		}
+		if err := cursor.Decode(&result); err != nil {
+			return nil, err
+		}
+		results = append(results, exerciseSearchResult{Exercise: result.Exercise, Score: utils.Round(result.Score, 3)})
+	}
+	return results, cursor.Err()
+}
+
+// fuzzySearch scores every exercise matching filter by how closely the query words match the
+// words of its name, category and muscles. The catalog is small enough to rank in memory.
+func (ec *ExerciseGuideController) fuzzySearch(ctx context.Context, query string, filter bson.M, limit int64) ([]exerciseSearchResult, error) {
+	queryTokens := searchTokens(query)
+	if len(queryTokens) == 0 {
+		return []exerciseSearchResult{}, nil
+	}
+
+	cursor, err := ec.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	results := make([]exerciseSearchResult, 0)
+	for cursor.Next(ctx) {
+		var exercise models.Exercise
+		if err := cursor.Decode(&exercise); err != nil {
+			return nil, err
+		}
+
+		candidates := searchTokens(exercise.Name + " " + exercise.Category + " " + strings.Join(exercise.MainMuscles, " "))
+		var total float64
+		for _, qt := range queryTokens {
+			best := 0.0
+			for _, ct := range candidates {
+				s := utils.Similarity(qt, ct)
+				// A query word that starts a longer word (e.g. "glute" in "glutes") is a strong match
+				if len(qt) >= 3 && strings.HasPrefix(ct, qt) {
+					s = max(s, 0.9)
+				}
+				best = max(best, s)
+			}
+			total += best
+		}
+
+		if score := total / float64(len(queryTokens)); score >= fuzzyMatchThreshold {
+			results = append(results, exerciseSearchResult{Exercise: exercise, Score: utils.Round(score, 3)})
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if int64(len(results)) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// searchTokens lowercases text and splits it into words
+func searchTokens(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -3,6 +3,8 @@ package controllers
 import (
 	"context"
 	"fitness-backend/models"
+	"fitness-backend/units"
+	"fitness-backend/utils"
 	"fmt"
 	"net/http"
 	"time"
@@ -34,9 +36,20 @@ func (fc *FoodController) AddFoodItem(c echo.Context) error {
 	if err := c.Bind(&foodItem); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
 	}
+	// A serving size in the user's units takes precedence over serving_size_g
+	if foodItem.ServingUnit != "" && foodItem.ServingSize > 0 {
+		foodItem.ServingSizeG = units.Convert(foodItem.ServingSize, foodItem.ServingUnit, units.Gram)
+	}
+	if err := c.Validate(&foodItem); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err))
+	}
 
 	foodItem.ID = primitive.NewObjectID()
-	foodItem.ConsumedAt = time.Now()
+	// Clients may backfill a meal; anything missing or in the future is stamped with the current time
+	if foodItem.ConsumedAt.IsZero() || foodItem.ConsumedAt.After(time.Now()) {
+		foodItem.ConsumedAt = time.Now()
+	}
+	foodItem.ConsumedDate = utils.CalendarDate(foodItem.ConsumedAt, utils.UserLocation(c))
 
 	collection := fc.db.Collection("FoodConsumed")
 	ctx := context.Background()
@@ -57,6 +70,7 @@ func (fc *FoodController) AddFoodItem(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to add food item"})
 	}
 
+	localizeFoodItem(&foodItem, utils.UserUnits(c))
 	return c.JSON(http.StatusCreated, foodItem)
 }
 
@@ -80,6 +94,32 @@ func (fc *FoodController) GetUserFoodItems(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch food items"})
 	}
 
+	// Optional ?date= (or ?date=today) narrows the list to one local day
+	if dateParam := c.QueryParam("date"); dateParam != "" {
+		date, err := utils.ResolveDate(c, dateParam)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid date format"))
+		}
+		loc := utils.UserLocation(c)
+		items := make([]models.FoodItem, 0)
+		for _, item := range foodConsumed.FoodItems {
+			// Items logged before consumedDate existed fall back to their timestamp
+			itemDate := item.ConsumedDate
+			if itemDate.IsZero() {
+				itemDate = utils.CalendarDate(item.ConsumedAt, loc)
+			}
+			if itemDate.Equal(date) {
+				items = append(items, item)
+			}
+		}
+		foodConsumed.FoodItems = items
+	}
+
+	system := utils.UserUnits(c)
+	for i := range foodConsumed.FoodItems {
+		localizeFoodItem(&foodConsumed.FoodItems[i], system)
+	}
+
 	return c.JSON(http.StatusOK, foodConsumed)
 }
 
@@ -116,3 +156,9 @@ func (fc *FoodController) DeleteFoodItem(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, map[string]string{"message": "Food item deleted successfully"})
 }
+
+// localizeFoodItem fills the display serving size in the user's unit system
+func localizeFoodItem(item *models.FoodItem, system string) {
+	item.ServingSize, item.ServingUnit = units.ToDisplay(item.ServingSizeG, units.Gram, system)
+	item.ServingSize = utils.Round(item.ServingSize, 2)
+}
@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"context"
+	"fitness-backend/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// pushExerciseGoals appends goals to a user's daily_data document for a date, creating the
+// document if needed. Goals whose name the day already has are skipped. The check and the
+// write happen in one update, so concurrent requests cannot add the same goal twice; a
+// unique index keeps the day to one document, and an upsert that loses the race to create
+// it is retried against the document the other request created.
+func pushExerciseGoals(ctx context.Context, collection *mongo.Collection, userID primitive.ObjectID, date time.Time, goals []models.ExerciseGoal) (added []models.ExerciseGoal, skipped []string, err error) {
+	return pushGoals(ctx, collection, userID, date, goals, func(goal models.ExerciseGoal) string { return goal.GoalName })
+}
+
+// pushGoals is pushExerciseGoals for any kind of goal; name returns a goal's goalName
+func pushGoals[G any](ctx context.Context, collection *mongo.Collection, userID primitive.ObjectID, date time.Time, goals []G, name func(G) string) (added []G, skipped []string, err error) {
+	if len(goals) == 0 {
+		return []G{}, []string{}, nil
+	}
+
+	existingNames := bson.M{"$ifNull": bson.A{"$goals.goalName", bson.A{}}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"goals": bson.M{"$concatArrays": bson.A{
+				bson.M{"$ifNull": bson.A{"$goals", bson.A{}}},
+				bson.M{"$filter": bson.M{
+					// $literal keeps goal values such as "$5 challenge" from being read as field paths
+					"input": bson.M{"$literal": goals},
+					"as":    "goal",
+					"cond":  bson.M{"$not": bson.A{bson.M{"$in": bson.A{"$$goal.goalName", existingNames}}}},
+				}},
+			}},
+		}}},
+	}
+
+	var before models.DailyDataCollection
+	for attempt := 0; attempt < 2; attempt++ {
+		before = models.DailyDataCollection{}
+		err = collection.FindOneAndUpdate(ctx,
+			bson.M{"userId": userID, "date": date},
+			pipeline,
+			options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before),
+		).Decode(&before)
+		if !mongo.IsDuplicateKeyError(err) {
+			break
+		}
+	}
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, nil, err
+	}
+
+	// The document as it was before the update tells which goals were already there
+	existing := map[string]bool{}
+	for _, goal := range before.Goals {
+		if goalMap, ok := goalAsMap(goal); ok {
+			if name, ok := goalMap["goalName"].(string); ok {
+				existing[name] = true
+			}
+		}
+	}
+
+	added, skipped = []G{}, []string{}
+	for _, goal := range goals {
+		if existing[name(goal)] {
+			skipped = append(skipped, name(goal))
+		} else {
+			added = append(added, goal)
+		}
+	}
+	return added, skipped, nil
+}
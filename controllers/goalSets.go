@@ -0,0 +1,236 @@
+package controllers
+
+import (
+	"context"
+	"fitness-backend/models"
+	"fitness-backend/units"
+	"fitness-backend/utils"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// maxSetsPerGoal caps the sets logged on one goal
+	maxSetsPerGoal = 100
+	// setSaveAttempts is how often a set change is retried when the goal changes underneath it
+	setSaveAttempts = 3
+)
+
+// AddSet logs a performed set on an exercise goal. Weight and distance are in the user's
+// units. Once a goal has sets, its progress is their total.
+func (gc *GoalController) AddSet(c echo.Context) error {
+	set, err := bindSet(c)
+	if err != nil {
+		return err
+	}
+	set.ID = primitive.NewObjectID()
+	set.LoggedAt = time.Now()
+
+	return gc.changeSets(c, http.StatusCreated, func(goal *models.ExerciseGoal) error {
+		if len(goal.Sets) >= maxSetsPerGoal {
+			return echo.NewHTTPError(http.StatusBadRequest, utils.ErrorResponse("A goal can have at most 100 sets"))
+		}
+		goal.Sets = append(goal.Sets, set)
+		return nil
+	})
+}
+
+// UpdateSet replaces the measurements of a logged set
+func (gc *GoalController) UpdateSet(c echo.Context) error {
+	setID, err := primitive.ObjectIDFromHex(c.Param("setId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid set ID format"))
+	}
+	set, err := bindSet(c)
+	if err != nil {
+		return err
+	}
+
+	return gc.changeSets(c, http.StatusOK, func(goal *models.ExerciseGoal) error {
+		for i := range goal.Sets {
+			if goal.Sets[i].ID == setID {
+				set.ID, set.LoggedAt = setID, goal.Sets[i].LoggedAt
+				goal.Sets[i] = set
+				return nil
+			}
+		}
+		return echo.NewHTTPError(http.StatusNotFound, utils.ErrorResponse("Set not found"))
+	})
+}
+
+// DeleteSet removes a logged set
+func (gc *GoalController) DeleteSet(c echo.Context) error {
+	setID, err := primitive.ObjectIDFromHex(c.Param("setId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid set ID format"))
+	}
+
+	return gc.changeSets(c, http.StatusOK, func(goal *models.ExerciseGoal) error {
+		for i := range goal.Sets {
+			if goal.Sets[i].ID == setID {
+				goal.Sets = append(goal.Sets[:i], goal.Sets[i+1:]...)
+				return nil
+			}
+		}
+		return echo.NewHTTPError(http.StatusNotFound, utils.ErrorResponse("Set not found"))
+	})
+}
+
+// bindSet reads a set from the request body and converts it into storage units, returning
+// an *echo.HTTPError on failure
+func bindSet(c echo.Context) (models.ExerciseSet, error) {
+	var set models.ExerciseSet
+	if err := c.Bind(&set); err != nil {
+		return set, echo.NewHTTPError(http.StatusBadRequest, utils.ErrorResponse("Invalid request body"))
+	}
+
+	system := utils.UserUnits(c)
+	set.Weight = units.Convert(set.Weight, units.DisplayUnit(units.Kilogram, system), units.Kilogram)
+	set.Distance = units.Convert(set.Distance, units.DisplayUnit(units.Kilometer, system), units.Kilometer)
+
+	if err := c.Validate(&set); err != nil {
+		return set, echo.NewHTTPError(http.StatusBadRequest, utils.ValidationErrorResponse(err))
+	}
+	if set.Reps == 0 && set.DurationSeconds == 0 && set.Distance == 0 {
+		return set, echo.NewHTTPError(http.StatusBadRequest, utils.ErrorResponse("A set needs reps, durationSeconds or distance"))
+	}
+	return set, nil
+}
+
+// changeSets applies change to the sets of the goal named by :id on :date and responds with
+// the saved goal
+func (gc *GoalController) changeSets(c echo.Context, status int, change func(goal *models.ExerciseGoal) error) error {
+	userID := actorID(c)
+	if userID.IsZero() {
+		return c.JSON(http.StatusUnauthorized, utils.ErrorResponse("Unauthorized"))
+	}
+	goalID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid goal ID format"))
+	}
+	date, err := utils.ResolveDate(c, c.Param("date"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid date format"))
+	}
+
+	goal, err := updateGoalSets(c.Request().Context(), gc.Collection, userID, date, goalID, change)
+	if err != nil {
+		return err
+	}
+	gc.trackGoalRecords(c.Request().Context(), userID, date, goal)
+	return c.JSON(status, localizeGoalResponse(goal, utils.UserUnits(c)))
+}
+
+// updateGoalSets applies change to the sets of a goal and saves them with the progress they
+// add up to, returning an *echo.HTTPError on failure. Progress recorded before the goal's
+// first set is kept and added to the sets' total. The save only lands if the goal is
+// unchanged since it was read, and is retried otherwise, so concurrent set changes are not
+// lost.
+func updateGoalSets(ctx context.Context, collection *mongo.Collection, userID primitive.ObjectID, date time.Time, goalID primitive.ObjectID, change func(goal *models.ExerciseGoal) error) (models.ExerciseGoal, error) {
+	for attempt := 0; attempt < setSaveAttempts; attempt++ {
+		var dailyData struct {
+			Goals []bson.Raw `bson:"goals"`
+		}
+		err := collection.FindOne(ctx,
+			bson.M{"userId": userID, "date": date, "goals._id": goalID},
+			options.FindOne().SetProjection(bson.M{"goals.$": 1}),
+		).Decode(&dailyData)
+		if err == mongo.ErrNoDocuments || (err == nil && len(dailyData.Goals) == 0) {
+			return models.ExerciseGoal{}, echo.NewHTTPError(http.StatusNotFound, utils.ErrorResponse("Goal not found"))
+		} else if err != nil {
+			return models.ExerciseGoal{}, echo.NewHTTPError(http.StatusInternalServerError, utils.ErrorResponse("Error retrieving goal"))
+		}
+
+		var goal models.ExerciseGoal
+		if err := bson.Unmarshal(dailyData.Goals[0], &goal); err != nil {
+			return goal, echo.NewHTTPError(http.StatusInternalServerError, utils.ErrorResponse("Error decoding goal"))
+		}
+		if goal.Type != "reps" && goal.Type != "mins" && goal.Type != "kms" {
+			return goal, echo.NewHTTPError(http.StatusBadRequest, utils.ErrorResponse("Sets can only be logged on exercise goals"))
+		}
+
+		// Goals saved before timestamps existed have no updatedAt to compare against
+		var readAt interface{} = goal.UpdatedAt
+		if goal.UpdatedAt.IsZero() {
+			readAt = bson.M{"$in": bson.A{nil, time.Time{}}}
+		}
+
+		if len(goal.Sets) == 0 {
+			goal.ManualProgress = goal.ProgressValue
+		}
+		if err := change(&goal); err != nil {
+			return goal, err
+		}
+		goal.ProgressValue = utils.Round(goal.ManualProgress+models.SetsProgress(goal.Type, goal.Sets), 2)
+		goal.UpdatedAt = time.Now()
+
+		set := bson.M{
+			"goals.$.progressValue": goal.ProgressValue,
+			"goals.$.updatedAt":     goal.UpdatedAt,
+		}
+		unset := bson.M{}
+		if len(goal.Sets) > 0 {
+			set["goals.$.sets"] = goal.Sets
+		} else {
+			// Without sets the goal goes back to plain progress
+			goal.ManualProgress = 0
+			unset["goals.$.sets"] = ""
+		}
+		if goal.ManualProgress > 0 {
+			set["goals.$.manualProgress"] = goal.ManualProgress
+		} else {
+			unset["goals.$.manualProgress"] = ""
+		}
+		update := bson.M{"$set": set}
+		if len(unset) > 0 {
+			update["$unset"] = unset
+		}
+
+		result, err := collection.UpdateOne(ctx, bson.M{
+			"userId": userID,
+			"date":   date,
+			"goals":  bson.M{"$elemMatch": bson.M{"_id": goalID, "updatedAt": readAt}},
+		}, update)
+		if err != nil {
+			return goal, echo.NewHTTPError(http.StatusInternalServerError, utils.ErrorResponse("Failed to save sets"))
+		}
+		if result.MatchedCount > 0 {
+			return goal, nil
+		}
+	}
+	return models.ExerciseGoal{}, echo.NewHTTPError(http.StatusConflict, utils.ErrorResponse("Goal is being changed by another request; try again"))
+}
+
+// hasSets reports whether a stored goal has logged sets
+func hasSets(goal map[string]interface{}) bool {
+	switch sets := goal["sets"].(type) {
+	case primitive.A:
+		return len(sets) > 0
+	case []interface{}:
+		return len(sets) > 0
+	default:
+		return false
+	}
+}
+
+// checkSetsUntouched returns an *echo.HTTPError when a goal update writes sets or
+// manualProgress, which only the sets endpoints may change, or progressValue on a goal whose
+// progress is its sets' total
+func checkSetsUntouched(update, existing map[string]interface{}) error {
+	if _, ok := update["sets"]; ok {
+		return echo.NewHTTPError(http.StatusBadRequest, utils.ErrorResponse("Use the sets endpoints to change a goal's sets"))
+	}
+	if _, ok := update["manualProgress"]; ok {
+		return echo.NewHTTPError(http.StatusBadRequest, utils.ErrorResponse("manualProgress is kept from progress recorded before the first set"))
+	}
+	if _, ok := update["progressValue"]; ok && hasSets(existing) {
+		return echo.NewHTTPError(http.StatusConflict, utils.ErrorResponse("This goal's progress is the total of its sets"))
+	}
+	return nil
+}
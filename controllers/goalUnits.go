@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fitness-backend/units"
+	"fitness-backend/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Goals are stored in canonical units (kg, kms, L, g). Weight goals carry their unit in
+// "unit", every other goal carries it in "type" alongside non-convertible types like "reps".
+
+// goalValueKeys are the goal fields holding a quantity in the goal's unit
+var goalValueKeys = []string{"goalValue", "progressValue", "manualProgress", "currentValue"}
+
+// goalAsMap converts a goal as decoded from daily_data into a plain map
+func goalAsMap(goal interface{}) (map[string]interface{}, bool) {
+	switch g := goal.(type) {
+	case primitive.D:
+		return g.Map(), true
+	case bson.M:
+		return g, true
+	case map[string]interface{}:
+		return g, true
+	default:
+		return nil, false
+	}
+}
+
+// goalUnitKey returns the field holding the unit of a goal
+func goalUnitKey(goal map[string]interface{}) string {
+	if _, ok := goal["unit"]; ok {
+		return "unit"
+	}
+	return "type"
+}
+
+// findGoalByName returns the goal with the given name from a daily_data document,
+// or an empty map when there is none
+func findGoalByName(goals []interface{}, name string) map[string]interface{} {
+	for _, goal := range goals {
+		if goalMap, ok := goalAsMap(goal); ok && goalMap["goalName"] == name {
+			return goalMap
+		}
+	}
+	return map[string]interface{}{}
+}
+
+// localizeGoal converts a stored goal's values into the user's unit system in place
+func localizeGoal(goal map[string]interface{}, system string) map[string]interface{} {
+	localizeSets(goal, system)
+	unitKey := goalUnitKey(goal)
+	stored, _ := goal[unitKey].(string)
+	if !units.IsConvertible(stored) {
+		return goal
+	}
+	display := units.DisplayUnit(units.Canonical(stored), system)
+	if display == stored {
+		return goal
+	}
+
+	convert := func(v interface{}) interface{} {
+		value, ok := utils.ConvertToFloat(v)
+		if !ok {
+			return v
+		}
+		return utils.Round(units.Convert(value, stored, display), 2)
+	}
+	for _, key := range goalValueKeys {
+		if v, ok := goal[key]; ok {
+			goal[key] = convert(v)
+		}
+	}
+	convertEntries(goal, convert)
+	goal[unitKey] = display
+	return goal
+}
+
+// localizeGoalResponse converts a goal struct or map into the user's unit system for a response
+func localizeGoalResponse(goal interface{}, system string) interface{} {
+	if system != units.Imperial {
+		return goal
+	}
+	goalMap, ok := goalAsMap(goal)
+	if !ok {
+		// Structs are converted through their JSON form so the response keeps its field names
+		b, err := json.Marshal(goal)
+		if err != nil || json.Unmarshal(b, &goalMap) != nil {
+			return goal
+		}
+	}
+	return localizeGoal(goalMap, system)
+}
+
+// canonicalizeGoalInput converts goal values sent by a client into storage units in place.
+// unitKey names the field holding the unit. A unit named in the request is converted to its
+// canonical unit; otherwise values are taken to be in the user's display unit and converted
+// to storedUnit, the unit of the goal being updated (or the default unit for a new goal).
+func canonicalizeGoalInput(goal map[string]interface{}, unitKey, storedUnit, system string) {
+	unit, _ := goal[unitKey].(string)
+	target := units.Canonical(unit)
+	if unit == "" {
+		unit = units.DisplayUnit(units.Canonical(storedUnit), system)
+		target = storedUnit
+	}
+	if !units.IsConvertible(unit) {
+		return
+	}
+
+	convert := func(v interface{}) interface{} {
+		value, ok := utils.ConvertToFloat(v)
+		if !ok {
+			return v
+		}
+		return units.Convert(value, unit, target)
+	}
+	for _, key := range goalValueKeys {
+		if v, ok := goal[key]; ok {
+			goal[key] = convert(v)
+		}
+	}
+	convertEntries(goal, convert)
+	goal[unitKey] = target
+}
+
+// convertEntries applies convert to the values of a weight goal's entries
+func convertEntries(goal map[string]interface{}, convert func(interface{}) interface{}) {
+	var entries []interface{}
+	switch e := goal["entries"].(type) {
+	case primitive.A:
+		entries = e
+	case []interface{}:
+		entries = e
+	default:
+		return
+	}
+
+	converted := make([]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		entryMap, ok := goalAsMap(entry)
+		if !ok {
+			converted = append(converted, entry)
+			continue
+		}
+		copied := make(map[string]interface{}, len(entryMap))
+		for k, v := range entryMap {
+			copied[k] = v
+		}
+		if v, ok := copied["value"]; ok {
+			copied["value"] = convert(v)
+		}
+		converted = append(converted, copied)
+	}
+	goal["entries"] = converted
+}
+
+// setQuantities maps the measured fields of an exercise set to their canonical unit
+var setQuantities = map[string]string{
+	"weight":   units.Kilogram,
+	"distance": units.Kilometer,
+}
+
+// localizeSets converts the weight and distance of an exercise goal's sets into the user's
+// unit system in place. Sets are converted whatever the goal's own unit, since a "reps" goal
+// still records the load lifted.
+func localizeSets(goal map[string]interface{}, system string) {
+	var sets []interface{}
+	switch s := goal["sets"].(type) {
+	case primitive.A:
+		sets = s
+	case []interface{}:
+		sets = s
+	default:
+		return
+	}
+
+	converted := make([]interface{}, 0, len(sets))
+	for _, set := range sets {
+		setMap, ok := goalAsMap(set)
+		if !ok {
+			converted = append(converted, set)
+			continue
+		}
+		copied := make(map[string]interface{}, len(setMap))
+		for k, v := range setMap {
+			copied[k] = v
+		}
+		for key, canonical := range setQuantities {
+			if value, ok := utils.ConvertToFloat(copied[key]); ok {
+				display, _ := units.ToDisplay(value, canonical, system)
+				copied[key] = utils.Round(display, 2)
+			}
+		}
+		converted = append(converted, copied)
+	}
+	goal["sets"] = converted
+}
@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"context"
+	"fitness-backend/mailer"
+	"fitness-backend/models"
+	"fitness-backend/utils"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	passwordResetTTL = time.Hour
+	// At most this many reset emails are sent per address within actionTokenRateWindow
+	actionTokenRateLimit  = 3
+	actionTokenRateWindow = time.Hour
+)
+
+// ForgotPassword emails a one-time reset token. The response is the same whether or not
+// the email belongs to an account so it cannot be used to discover registered addresses.
+// Only accounts can reach the rate limit, so requests over it get that response too, with
+// no email sent.
+func (ac *AuthController) ForgotPassword(c echo.Context) error {
+	var req struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid request body"))
+	}
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err))
+	}
+
+	ctx := c.Request().Context()
+	response := utils.SuccessResponse("If an account exists for this email, a reset link has been sent")
+
+	limited, err := ac.actionTokenRateLimited(ctx, req.Email, models.TokenPurposePasswordReset)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error checking reset requests"))
+	}
+	if limited {
+		return c.JSON(http.StatusOK, response)
+	}
+
+	var user models.User
+	err = ac.collection.FindOne(ctx, bson.M{"email": req.Email}).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return c.JSON(http.StatusOK, response)
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error fetching user"))
+	}
+
+	token, err := ac.createActionToken(ctx, user, models.TokenPurposePasswordReset, passwordResetTTL)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error creating reset token"))
+	}
+
+	body := fmt.Sprintf("Use this code to reset your password: %s\n", token)
+	if resetURL := utils.GetEnvVariable("PASSWORD_RESET_URL"); resetURL != "" {
+		body += fmt.Sprintf("\nOr open %s?token=%s\n", resetURL, token)
+	}
+	body += fmt.Sprintf("\nThe code expires in %d minutes. If you did not ask for a reset, ignore this email.", int(passwordResetTTL.Minutes()))
+
+	err = ac.Mailer.Send(ctx, mailer.Message{To: user.Email, Subject: "Reset your password", Body: body, Secrets: []string{token}})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error sending reset email"))
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// ResetPassword consumes a reset token, sets the new password and signs the user out everywhere
+func (ac *AuthController) ResetPassword(c echo.Context) error {
+	var req struct {
+		Token    string `json:"token" validate:"required"`
+		Password string `json:"password" validate:"required,min=8"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid request body"))
+	}
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err))
+	}
+
+	ctx := c.Request().Context()
+	resetToken, err := ac.consumeActionToken(ctx, req.Token, models.TokenPurposePasswordReset)
+	if err == mongo.ErrNoDocuments {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid or expired reset token"))
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error reading reset token"))
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error hashing password"))
+	}
+
+	result, err := ac.collection.UpdateOne(ctx, bson.M{"_id": resetToken.UserID}, bson.M{"$set": bson.M{"password": string(hashedPassword)}})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error updating password"))
+	}
+	if result.MatchedCount == 0 {
+		return c.JSON(http.StatusNotFound, utils.ErrorResponse("User not found"))
+	}
+
+	// Any session opened with the old password is no longer trusted
+	now := time.Now()
+	_, err = ac.sessionCollection.UpdateMany(ctx,
+		bson.M{"userId": resetToken.UserID, "revokedAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revokedAt": now, "revokedReason": "password_reset"}},
+	)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error revoking sessions"))
+	}
+
+	// Other outstanding reset tokens for this account are invalidated as well
+	_, err = ac.actionTokenCollection.UpdateMany(ctx,
+		bson.M{"userId": resetToken.UserID, "purpose": models.TokenPurposePasswordReset, "usedAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"usedAt": now}},
+	)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error invalidating reset tokens"))
+	}
+
+	return c.JSON(http.StatusOK, utils.SuccessResponse("Password reset successfully"))
+}
+
+// actionTokenRateLimited reports whether too many tokens of this purpose were issued for the email recently
+func (ac *AuthController) actionTokenRateLimited(ctx context.Context, email, purpose string) (bool, error) {
+	count, err := ac.actionTokenCollection.CountDocuments(ctx, bson.M{
+		"email":     email,
+		"purpose":   purpose,
+		"createdAt": bson.M{"$gt": time.Now().Add(-actionTokenRateWindow)},
+	})
+	if err != nil {
+		return false, err
+	}
+	return count >= actionTokenRateLimit, nil
+}
+
+// createActionToken stores the hash of a new one-time token and returns the raw token
+func (ac *AuthController) createActionToken(ctx context.Context, user models.User, purpose string, ttl time.Duration) (string, error) {
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	_, err = ac.actionTokenCollection.InsertOne(ctx, models.ActionToken{
+		ID:        primitive.NewObjectID(),
+		UserID:    user.ID,
+		Email:     user.Email,
+		Purpose:   purpose,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// consumeActionToken atomically marks an unused, unexpired token as used.
+// It returns mongo.ErrNoDocuments when the token is unknown, used or expired.
+func (ac *AuthController) consumeActionToken(ctx context.Context, token, purpose string) (models.ActionToken, error) {
+	now := time.Now()
+	var actionToken models.ActionToken
+	err := ac.actionTokenCollection.FindOneAndUpdate(ctx,
+		bson.M{
+			"tokenHash": utils.HashToken(token),
+			"purpose":   purpose,
+			"usedAt":    bson.M{"$exists": false},
+			"expiresAt": bson.M{"$gt": now},
+		},
+		bson.M{"$set": bson.M{"usedAt": now}},
+	).Decode(&actionToken)
+	return actionToken, err
+}
@@ -0,0 +1,377 @@
+package controllers
+
+import (
+	"context"
+	"fitness-backend/models"
+	"fitness-backend/units"
+	"fitness-backend/utils"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// recordKeeper updates a user's personal records as exercise progress is recorded
+type recordKeeper struct {
+	records *mongo.Collection
+}
+
+func newRecordKeeper(db *mongo.Database) *recordKeeper {
+	return &recordKeeper{records: db.Collection("personal_records")}
+}
+
+// recordCandidate is a performance that may beat a personal record
+type recordCandidate struct {
+	Kind  string
+	Value float64
+	SetID *primitive.ObjectID
+}
+
+// recordCandidates returns the best performance of each kind in a goal. Sets give the
+// weight, reps, one-rep max, pace and distance of each effort; the goal's progress gives
+// the day's total reps or distance, with or without sets.
+func recordCandidates(goal models.ExerciseGoal) []recordCandidate {
+	best := map[string]recordCandidate{}
+	consider := func(kind string, value float64, setID *primitive.ObjectID) {
+		value = utils.Round(value, 2)
+		if value <= 0 {
+			return
+		}
+		current, ok := best[kind]
+		if !ok || (models.LowerIsBetter(kind) && value < current.Value) || (!models.LowerIsBetter(kind) && value > current.Value) {
+			best[kind] = recordCandidate{Kind: kind, Value: value, SetID: setID}
+		}
+	}
+
+	switch goal.Type {
+	case "reps":
+		consider(models.RecordDailyReps, goal.ProgressValue, nil)
+	case "kms":
+		consider(models.RecordDailyDistance, goal.ProgressValue, nil)
+	}
+	for i := range goal.Sets {
+		set := goal.Sets[i]
+		setID := &set.ID
+		consider(models.RecordMaxWeight, set.Weight, setID)
+		consider(models.RecordMaxReps, float64(set.Reps), setID)
+		consider(models.RecordEstimated1RM, utils.EstimateOneRepMax(set.Weight, set.Reps), setID)
+		consider(models.RecordLongestDistance, set.Distance, setID)
+		if set.Distance > 0 && set.DurationSeconds > 0 {
+			consider(models.RecordFastestPace, float64(set.DurationSeconds)/60/set.Distance, setID)
+		}
+	}
+
+	candidates := make([]recordCandidate, 0, len(best))
+	for _, candidate := range best {
+		candidates = append(candidates, candidate)
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Kind < candidates[j].Kind })
+	return candidates
+}
+
+// track records the personal bests a goal beats and returns them. Goals that are not linked
+// to an exercise cannot set records. Each record only moves when the new value beats the
+// stored one, in a single update, so concurrent progress cannot lower a record.
+func (rk *recordKeeper) track(ctx context.Context, userID primitive.ObjectID, date time.Time, goal models.ExerciseGoal) ([]models.PersonalRecord, error) {
+	beaten := []models.PersonalRecord{}
+	if goal.ExerciseID.IsZero() {
+		return beaten, nil
+	}
+
+	now := time.Now()
+	for _, candidate := range recordCandidates(goal) {
+		beats := bson.M{"$lt": candidate.Value}
+		if models.LowerIsBetter(candidate.Kind) {
+			beats = bson.M{"$gt": candidate.Value}
+		}
+		entry := models.RecordEntry{
+			Value:      candidate.Value,
+			Date:       date,
+			GoalID:     goal.ID,
+			SetID:      candidate.SetID,
+			AchievedAt: now,
+		}
+
+		var record models.PersonalRecord
+		err := rk.records.FindOneAndUpdate(ctx,
+			bson.M{"userId": userID, "exerciseId": goal.ExerciseID, "kind": candidate.Kind, "value": beats},
+			bson.M{
+				"$set": bson.M{
+					"value":      candidate.Value,
+					"unit":       models.RecordUnits[candidate.Kind],
+					"date":       date,
+					"goalId":     goal.ID,
+					"setId":      candidate.SetID,
+					"achievedAt": now,
+				},
+				"$push": bson.M{"history": entry},
+			},
+			options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+		).Decode(&record)
+		if mongo.IsDuplicateKeyError(err) {
+			// The stored record is at least as good, so the upsert tried to add a second one
+			continue
+		} else if err != nil {
+			return beaten, err
+		}
+		beaten = append(beaten, record)
+	}
+	return beaten, nil
+}
+
+// reassign moves the personal records set on one exercise to another, as when the first is
+// deleted in favor of the second. Where a user already has a record of the same kind on
+// the target, the two histories are merged and the better value becomes the record.
+func (rk *recordKeeper) reassign(ctx context.Context, from, to primitive.ObjectID) error {
+	cursor, err := rk.records.Find(ctx, bson.M{"exerciseId": from})
+	if err != nil {
+		return err
+	}
+	var moving []models.PersonalRecord
+	if err := cursor.All(ctx, &moving); err != nil {
+		return err
+	}
+
+	for _, record := range moving {
+		var existing models.PersonalRecord
+		err := rk.records.FindOne(ctx, bson.M{"userId": record.UserID, "exerciseId": to, "kind": record.Kind}).Decode(&existing)
+		if err == mongo.ErrNoDocuments {
+			if _, err := rk.records.UpdateOne(ctx, bson.M{"_id": record.ID}, bson.M{"$set": bson.M{"exerciseId": to}}); err != nil {
+				return err
+			}
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		history := mergeRecordHistory(record.Kind, existing.History, record.History)
+		if len(history) > 0 {
+			best := history[len(history)-1]
+			_, err = rk.records.UpdateOne(ctx, bson.M{"_id": existing.ID}, bson.M{"$set": bson.M{
+				"value":      best.Value,
+				"date":       best.Date,
+				"goalId":     best.GoalID,
+				"setId":      best.SetID,
+				"achievedAt": best.AchievedAt,
+				"history":    history,
+			}})
+			if err != nil {
+				return err
+			}
+		}
+		if _, err := rk.records.DeleteOne(ctx, bson.M{"_id": record.ID}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeRecordHistory combines two histories of the same kind of record into one, oldest
+// first, keeping only the entries that beat every earlier one
+func mergeRecordHistory(kind string, a, b []models.RecordEntry) []models.RecordEntry {
+	entries := append(append([]models.RecordEntry{}, a...), b...)
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].Date.Equal(entries[j].Date) {
+			return entries[i].Date.Before(entries[j].Date)
+		}
+		return entries[i].AchievedAt.Before(entries[j].AchievedAt)
+	})
+
+	merged := []models.RecordEntry{}
+	for _, entry := range entries {
+		if len(merged) > 0 {
+			best := merged[len(merged)-1].Value
+			if models.LowerIsBetter(kind) && entry.Value >= best || !models.LowerIsBetter(kind) && entry.Value <= best {
+				continue
+			}
+		}
+		merged = append(merged, entry)
+	}
+	return merged
+}
+
+// trackGoalRecords updates personal records from a goal as stored in daily_data. Progress is
+// already saved when this runs, so a failure is logged rather than failing the request.
+func (gc *GoalController) trackGoalRecords(ctx context.Context, userID primitive.ObjectID, date time.Time, goal interface{}) {
+	var exerciseGoal models.ExerciseGoal
+	switch g := goal.(type) {
+	case models.ExerciseGoal:
+		exerciseGoal = g
+	default:
+		data, err := bson.Marshal(g)
+		if err != nil || bson.Unmarshal(data, &exerciseGoal) != nil {
+			return
+		}
+	}
+	if _, err := gc.records.track(ctx, userID, date, exerciseGoal); err != nil {
+		log.Println("Error updating personal records:", err)
+	}
+}
+
+// exerciseRecords groups an exercise's personal records for a response
+type exerciseRecords struct {
+	ExerciseID   primitive.ObjectID      `json:"exerciseId"`
+	ExerciseName string                  `json:"exerciseName"`
+	Records      []models.PersonalRecord `json:"records"`
+}
+
+// GetRecords lists the authenticated user's personal records by exercise, each with the
+// history of when it was set. Supports ?exerciseId= and ?kind=.
+func (gc *GoalController) GetRecords(c echo.Context) error {
+	userID := actorID(c)
+	if userID.IsZero() {
+		return c.JSON(http.StatusUnauthorized, utils.ErrorResponse("Unauthorized"))
+	}
+
+	filter := bson.M{"userId": userID}
+	if value := c.QueryParam("exerciseId"); value != "" {
+		exerciseID, err := primitive.ObjectIDFromHex(value)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid exercise ID"))
+		}
+		filter["exerciseId"] = exerciseID
+	}
+	if kind := c.QueryParam("kind"); kind != "" {
+		if _, ok := models.RecordUnits[kind]; !ok {
+			return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Unknown record kind"))
+		}
+		filter["kind"] = kind
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := gc.records.records.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "exerciseId", Value: 1}, {Key: "kind", Value: 1}}))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to fetch records"))
+	}
+	var records []models.PersonalRecord
+	if err := cursor.All(ctx, &records); err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error decoding records"))
+	}
+
+	ids := []primitive.ObjectID{}
+	grouped := map[primitive.ObjectID]*exerciseRecords{}
+	system := utils.UserUnits(c)
+	for _, record := range records {
+		group, ok := grouped[record.ExerciseID]
+		if !ok {
+			group = &exerciseRecords{ExerciseID: record.ExerciseID, Records: []models.PersonalRecord{}}
+			grouped[record.ExerciseID] = group
+			ids = append(ids, record.ExerciseID)
+		}
+		group.Records = append(group.Records, localizeRecord(record, system))
+	}
+
+	// Exercise names come from the guides; records of deleted guides keep an empty name
+	cursor, err = gc.ExerciseCollection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}}, options.Find().SetProjection(bson.M{"name": 1}))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to fetch exercises"))
+	}
+	var exercises []models.Exercise
+	if err := cursor.All(ctx, &exercises); err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error decoding exercises"))
+	}
+	for _, exercise := range exercises {
+		grouped[exercise.ID].ExerciseName = exercise.Name
+	}
+
+	response := make([]exerciseRecords, 0, len(ids))
+	for _, id := range ids {
+		response = append(response, *grouped[id])
+	}
+	sort.SliceStable(response, func(i, j int) bool { return response[i].ExerciseName < response[j].ExerciseName })
+	return c.JSON(http.StatusOK, response)
+}
+
+// DeleteRecord withdraws a personal record set by mistake. The previous best in its history
+// becomes the record again; a record with no earlier best is removed.
+func (gc *GoalController) DeleteRecord(c echo.Context) error {
+	userID := actorID(c)
+	if userID.IsZero() {
+		return c.JSON(http.StatusUnauthorized, utils.ErrorResponse("Unauthorized"))
+	}
+	recordID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid record ID"))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var record models.PersonalRecord
+	err = gc.records.records.FindOne(ctx, bson.M{"_id": recordID, "userId": userID}).Decode(&record)
+	if err == mongo.ErrNoDocuments {
+		return c.JSON(http.StatusNotFound, utils.ErrorResponse("Record not found"))
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to fetch record"))
+	}
+
+	// Matching the current value keeps a record beaten meanwhile from being rolled back
+	filter := bson.M{"_id": record.ID, "value": record.Value, "achievedAt": record.AchievedAt}
+	if len(record.History) <= 1 {
+		result, err := gc.records.records.DeleteOne(ctx, filter)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to delete record"))
+		}
+		if result.DeletedCount == 0 {
+			return c.JSON(http.StatusConflict, utils.ErrorResponse("Record changed; fetch it again"))
+		}
+		return c.JSON(http.StatusOK, utils.SuccessResponse("Record deleted successfully"))
+	}
+
+	history := record.History[:len(record.History)-1]
+	previous := history[len(history)-1]
+	var restored models.PersonalRecord
+	err = gc.records.records.FindOneAndUpdate(ctx, filter,
+		bson.M{"$set": bson.M{
+			"value":      previous.Value,
+			"date":       previous.Date,
+			"goalId":     previous.GoalID,
+			"setId":      previous.SetID,
+			"achievedAt": previous.AchievedAt,
+			"history":    history,
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&restored)
+	if err == mongo.ErrNoDocuments {
+		return c.JSON(http.StatusConflict, utils.ErrorResponse("Record changed; fetch it again"))
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to restore previous record"))
+	}
+	return c.JSON(http.StatusOK, localizeRecord(restored, utils.UserUnits(c)))
+}
+
+// localizeRecord converts a record and its history into the user's unit system. Pace is
+// shown per mile for imperial users.
+func localizeRecord(record models.PersonalRecord, system string) models.PersonalRecord {
+	convert := func(value float64) float64 { return value }
+	switch {
+	case record.Kind == models.RecordFastestPace && system == units.Imperial:
+		kmPerMile := units.Convert(1, units.Mile, units.Kilometer)
+		convert = func(value float64) float64 { return utils.Round(value*kmPerMile, 2) }
+		record.Unit = "min/mile"
+	case units.IsConvertible(record.Unit):
+		canonical := record.Unit
+		_, record.Unit = units.ToDisplay(0, canonical, system)
+		convert = func(value float64) float64 {
+			display, _ := units.ToDisplay(value, canonical, system)
+			return utils.Round(display, 2)
+		}
+	}
+
+	record.Value = convert(record.Value)
+	history := make([]models.RecordEntry, len(record.History))
+	for i, entry := range record.History {
+		entry.Value = convert(entry.Value)
+		history[i] = entry
+	}
+	record.History = history
+	return record
+}
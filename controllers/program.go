@@ -0,0 +1,267 @@
+package controllers
+
+import (
+	"context"
+	"fitness-backend/models"
+	"fitness-backend/units"
+	"fitness-backend/utils"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ProgramController struct {
+	collection           *mongo.Collection
+	enrollmentCollection *mongo.Collection
+	dailyCollection      *mongo.Collection
+	exerciseCollection   *mongo.Collection
+	scheduler            *programScheduler
+}
+
+// NewProgramController initializes a new instance of ProgramController
+func NewProgramController(db *mongo.Database) *ProgramController {
+	return &ProgramController{
+		collection:           db.Collection("training_programs"),
+		enrollmentCollection: db.Collection("program_enrollments"),
+		dailyCollection:      db.Collection("daily_data"),
+		exerciseCollection:   db.Collection("exercise_guides"),
+		scheduler:            newProgramScheduler(db),
+	}
+}
+
+// CreateProgram saves a new training program authored by the authenticated coach or admin
+func (pc *ProgramController) CreateProgram(c echo.Context) error {
+	program, err := pc.bindProgram(c)
+	if err != nil {
+		return err
+	}
+	program.ID = primitive.NewObjectID()
+	program.CreatedBy = actorID(c)
+	program.CreatedAt = time.Now()
+	program.UpdatedAt = program.CreatedAt
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := pc.collection.InsertOne(ctx, program); err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to create program"))
+	}
+	return c.JSON(http.StatusCreated, localizeProgram(program, utils.UserUnits(c)))
+}
+
+// GetPrograms lists all training programs by name
+func (pc *ProgramController) GetPrograms(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := pc.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to fetch programs"))
+	}
+	programs := make([]models.Program, 0)
+	if err := cursor.All(ctx, &programs); err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error decoding programs"))
+	}
+
+	system := utils.UserUnits(c)
+	for i := range programs {
+		programs[i] = localizeProgram(programs[i], system)
+	}
+	return c.JSON(http.StatusOK, programs)
+}
+
+// GetProgram returns a training program
+func (pc *ProgramController) GetProgram(c echo.Context) error {
+	program, err := pc.findProgram(c)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, localizeProgram(program, utils.UserUnits(c)))
+}
+
+// UpdateProgram replaces a training program. Only its author or an admin may change it.
+// Enrolled users get the new schedule from the next day that has no goals yet.
+func (pc *ProgramController) UpdateProgram(c echo.Context) error {
+	current, err := pc.findEditableProgram(c)
+	if err != nil {
+		return err
+	}
+
+	program, err := pc.bindProgram(c)
+	if err != nil {
+		return err
+	}
+	program.ID = current.ID
+	program.CreatedBy = current.CreatedBy
+	program.CreatedAt = current.CreatedAt
+	program.UpdatedAt = time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := pc.collection.ReplaceOne(ctx, bson.M{"_id": current.ID}, program); err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to update program"))
+	}
+	return c.JSON(http.StatusOK, localizeProgram(program, utils.UserUnits(c)))
+}
+
+// DeleteProgram removes a training program nobody is following. Only its author or an admin
+// may delete it.
+func (pc *ProgramController) DeleteProgram(c echo.Context) error {
+	program, err := pc.findEditableProgram(c)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	enrolled, err := pc.enrollmentCollection.CountDocuments(ctx, bson.M{
+		"programId": program.ID,
+		"status":    bson.M{"$in": bson.A{models.EnrollmentActive, models.EnrollmentPaused}},
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to check enrollments"))
+	}
+	if enrolled > 0 {
+		return c.JSON(http.StatusConflict, echo.Map{"error": "Program has active enrollments", "enrollments": enrolled})
+	}
+
+	if _, err := pc.collection.DeleteOne(ctx, bson.M{"_id": program.ID}); err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to delete program"))
+	}
+	return c.JSON(http.StatusOK, utils.SuccessResponse("Program deleted successfully"))
+}
+
+// findProgram loads the program named by :id, returning an *echo.HTTPError on failure
+func (pc *ProgramController) findProgram(c echo.Context) (models.Program, error) {
+	programID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return models.Program{}, echo.NewHTTPError(http.StatusBadRequest, utils.ErrorResponse("Invalid program ID"))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var program models.Program
+	err = pc.collection.FindOne(ctx, bson.M{"_id": programID}).Decode(&program)
+	if err == mongo.ErrNoDocuments {
+		return program, echo.NewHTTPError(http.StatusNotFound, utils.ErrorResponse("Program not found"))
+	} else if err != nil {
+		return program, echo.NewHTTPError(http.StatusInternalServerError, utils.ErrorResponse("Failed to fetch program"))
+	}
+	return program, nil
+}
+
+// findEditableProgram loads the program named by :id when the authenticated user wrote it
+// or is an admin
+func (pc *ProgramController) findEditableProgram(c echo.Context) (models.Program, error) {
+	program, err := pc.findProgram(c)
+	if err != nil {
+		return program, err
+	}
+	if role, _ := c.Get("role").(string); role != models.RoleAdmin && program.CreatedBy != actorID(c) {
+		return program, echo.NewHTTPError(http.StatusForbidden, utils.ErrorResponse("Only the program's author or an admin can change it"))
+	}
+	return program, nil
+}
+
+// bindProgram reads and validates a program from the request body, returning an
+// *echo.HTTPError on failure. Targets are converted to canonical units and each exercise is
+// linked to the global catalog, since programs are shared by everyone who enrolls.
+func (pc *ProgramController) bindProgram(c echo.Context) (models.Program, error) {
+	var program models.Program
+	if err := c.Bind(&program); err != nil {
+		return program, echo.NewHTTPError(http.StatusBadRequest, utils.ErrorResponse("Invalid request body"))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	days := map[int]bool{}
+	for i := range program.Workouts {
+		workout := &program.Workouts[i]
+		if days[workout.Day] {
+			return program, echo.NewHTTPError(http.StatusBadRequest, utils.ErrorResponse(fmt.Sprintf("Only one workout can be scheduled on day %d", workout.Day)))
+		}
+		days[workout.Day] = true
+
+		names := map[string]bool{}
+		for j := range workout.Exercises {
+			pe := &workout.Exercises[j]
+			unit := pe.Type
+			pe.GoalValue, pe.Type = units.ToCanonical(pe.GoalValue, unit)
+			pe.WeeklyIncrease, _ = units.ToCanonical(pe.WeeklyIncrease, unit)
+			for k := range pe.WeeklyTargets {
+				pe.WeeklyTargets[k], _ = units.ToCanonical(pe.WeeklyTargets[k], unit)
+			}
+
+			if !pe.ExerciseID.IsZero() {
+				var exercise models.Exercise
+				err := pc.exerciseCollection.FindOne(ctx, bson.M{"_id": pe.ExerciseID, "ownerId": nil, "deletedAt": nil}).Decode(&exercise)
+				if err == mongo.ErrNoDocuments {
+					return program, echo.NewHTTPError(http.StatusBadRequest, utils.ErrorResponse("Exercise not found in the catalog: "+pe.ExerciseID.Hex()))
+				} else if err != nil {
+					return program, echo.NewHTTPError(http.StatusInternalServerError, utils.ErrorResponse("Failed to fetch exercise"))
+				}
+				if pe.GoalName == "" {
+					pe.GoalName = exercise.Name
+				}
+			} else if pe.GoalName != "" {
+				var exercise models.Exercise
+				err := pc.exerciseCollection.FindOne(ctx, bson.M{"ownerId": nil, "name": pe.GoalName, "deletedAt": nil}).Decode(&exercise)
+				if err != nil && err != mongo.ErrNoDocuments {
+					return program, echo.NewHTTPError(http.StatusInternalServerError, utils.ErrorResponse("Failed to fetch exercise"))
+				}
+				pe.ExerciseID = exercise.ID
+			}
+
+			// Goal names identify goals within a day, so a workout cannot repeat one
+			if pe.GoalName != "" && names[pe.GoalName] {
+				return program, echo.NewHTTPError(http.StatusBadRequest, utils.ErrorResponse("Each exercise in a workout needs a different goalName: "+pe.GoalName))
+			}
+			names[pe.GoalName] = true
+		}
+	}
+
+	if err := c.Validate(&program); err != nil {
+		return program, echo.NewHTTPError(http.StatusBadRequest, utils.ValidationErrorResponse(err))
+	}
+	return program, nil
+}
+
+// localizeProgram converts a program's distance targets into the user's unit system
+func localizeProgram(program models.Program, system string) models.Program {
+	workouts := make([]models.ProgramWorkout, len(program.Workouts))
+	for i, workout := range program.Workouts {
+		exercises := make([]models.ProgramExercise, len(workout.Exercises))
+		for j, pe := range workout.Exercises {
+			if units.IsConvertible(pe.Type) {
+				canonical := pe.Type
+				value, unit := units.ToDisplay(pe.GoalValue, canonical, system)
+				pe.GoalValue, pe.Type = utils.Round(value, 2), unit
+				increase, _ := units.ToDisplay(pe.WeeklyIncrease, canonical, system)
+				pe.WeeklyIncrease = utils.Round(increase, 2)
+				targets := make([]float64, len(pe.WeeklyTargets))
+				for k, target := range pe.WeeklyTargets {
+					value, _ := units.ToDisplay(target, canonical, system)
+					targets[k] = utils.Round(value, 2)
+				}
+				if len(targets) > 0 {
+					pe.WeeklyTargets = targets
+				}
+			}
+			exercises[j] = pe
+		}
+		workout.Exercises = exercises
+		workouts[i] = workout
+	}
+	program.Workouts = workouts
+	return program
+}
@@ -0,0 +1,500 @@
+package controllers
+
+import (
+	"context"
+	"fitness-backend/models"
+	"fitness-backend/units"
+	"fitness-backend/utils"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// defaultScheduleDays is how far ahead a schedule preview looks by default
+	defaultScheduleDays = 14
+	// maxScheduleDays caps the range of a schedule preview
+	maxScheduleDays = 92
+)
+
+// enrollRequest starts a program; StartDate is "YYYY-MM-DD" or "today" and defaults to today
+type enrollRequest struct {
+	StartDate string `json:"startDate"`
+}
+
+// rescheduleRequest moves an enrollment's workouts. StartDate moves a program that has not
+// produced any goals yet; otherwise Days pushes every workout from From (default today) back.
+type rescheduleRequest struct {
+	StartDate string `json:"startDate"`
+	From      string `json:"from"`
+	Days      int    `json:"days" validate:"omitempty,gte=1,lte=90"`
+}
+
+// adherenceReport compares the workouts a program scheduled up to today with what was done
+type adherenceReport struct {
+	ScheduledDays int     `json:"scheduledDays"` // Workout days up to today
+	CompletedDays int     `json:"completedDays"` // Every goal of the workout reached
+	PartialDays   int     `json:"partialDays"`   // Some progress logged
+	MissedDays    int     `json:"missedDays"`    // No progress logged
+	RemainingDays int     `json:"remainingDays"` // Workout days after today
+	Rate          float64 `json:"rate"`          // Completed share of scheduled days
+}
+
+// scheduleEntry is one workout in a schedule preview
+type scheduleEntry struct {
+	Date         string                `json:"date"`
+	Week         int                   `json:"week"`
+	Workout      string                `json:"workout"`
+	Goals        []models.ExerciseGoal `json:"goals"`
+	Materialized bool                  `json:"materialized"` // Its goals already exist
+}
+
+// Enroll starts the authenticated user on a program from a start date
+func (pc *ProgramController) Enroll(c echo.Context) error {
+	userID := actorID(c)
+	if userID.IsZero() {
+		return c.JSON(http.StatusUnauthorized, utils.ErrorResponse("Unauthorized"))
+	}
+	program, err := pc.findProgram(c)
+	if err != nil {
+		return err
+	}
+
+	var req enrollRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid request body"))
+	}
+	today := utils.UserToday(c)
+	startDate := today
+	if req.StartDate != "" {
+		if startDate, err = utils.ResolveDate(c, req.StartDate); err != nil {
+			return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid date format"))
+		}
+		if startDate.Before(today) {
+			return c.JSON(http.StatusBadRequest, utils.ErrorResponse("startDate cannot be in the past"))
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	enrollment := models.Enrollment{
+		ID:                primitive.NewObjectID(),
+		UserID:            userID,
+		ProgramID:         program.ID,
+		StartDate:         startDate,
+		Status:            models.EnrollmentActive,
+		Pauses:            []models.EnrollmentPause{},
+		MaterializedDates: []time.Time{},
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+	// A unique index allows one active or paused enrollment per user and program
+	if _, err := pc.enrollmentCollection.InsertOne(ctx, enrollment); mongo.IsDuplicateKeyError(err) {
+		return c.JSON(http.StatusConflict, utils.ErrorResponse("You are already enrolled in this program"))
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to enroll"))
+	}
+
+	// A program starting today shows up in today's goals straight away
+	if err := pc.scheduler.materialize(ctx, userID, today, today); err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to schedule today's workout"))
+	}
+	return c.JSON(http.StatusCreated, enrollment)
+}
+
+// GetEnrollments lists the authenticated user's enrollments, newest first. Supports ?status=.
+func (pc *ProgramController) GetEnrollments(c echo.Context) error {
+	userID := actorID(c)
+	if userID.IsZero() {
+		return c.JSON(http.StatusUnauthorized, utils.ErrorResponse("Unauthorized"))
+	}
+
+	filter := bson.M{"userId": userID}
+	if status := c.QueryParam("status"); status != "" {
+		filter["status"] = status
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := pc.enrollmentCollection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to fetch enrollments"))
+	}
+	enrollments := make([]models.Enrollment, 0)
+	if err := cursor.All(ctx, &enrollments); err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error decoding enrollments"))
+	}
+	return c.JSON(http.StatusOK, enrollments)
+}
+
+// GetEnrollment returns an enrollment with its program, current week and adherence
+func (pc *ProgramController) GetEnrollment(c echo.Context) error {
+	enrollment, program, err := pc.findEnrollment(c)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	today := utils.UserToday(c)
+	adherence, err := pc.adherence(ctx, enrollment, program, today)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to compute adherence"))
+	}
+
+	var currentWeek int
+	if day, ok := enrollment.ProgramDay(today); ok && day < program.Weeks*7 {
+		currentWeek = day/7 + 1
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"enrollment":  enrollment,
+		"program":     localizeProgram(program, utils.UserUnits(c)),
+		"currentWeek": currentWeek,
+		"adherence":   adherence,
+	})
+}
+
+// GetEnrollmentSchedule previews the workouts an enrollment schedules between ?from= and
+// ?to= (YYYY-MM-DD or "today"), by default the next two weeks
+func (pc *ProgramController) GetEnrollmentSchedule(c echo.Context) error {
+	enrollment, program, err := pc.findEnrollment(c)
+	if err != nil {
+		return err
+	}
+
+	from := utils.UserToday(c)
+	if value := c.QueryParam("from"); value != "" {
+		if from, err = utils.ResolveDate(c, value); err != nil {
+			return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid from date"))
+		}
+	}
+	to := from.AddDate(0, 0, defaultScheduleDays-1)
+	if value := c.QueryParam("to"); value != "" {
+		if to, err = utils.ResolveDate(c, value); err != nil {
+			return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid to date"))
+		}
+	}
+	if to.Before(from) || to.Sub(from) >= maxScheduleDays*24*time.Hour {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("to must be on or after from and at most 92 days later"))
+	}
+
+	system := utils.UserUnits(c)
+	now := time.Now()
+	schedule := make([]scheduleEntry, 0)
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		scheduled, ok := workoutOn(enrollment, program, date)
+		if !ok {
+			continue
+		}
+		goals := scheduled.goals(enrollment, now)
+		for i := range goals {
+			if units.IsConvertible(goals[i].Type) {
+				value, unit := units.ToDisplay(goals[i].GoalValue, goals[i].Type, system)
+				goals[i].GoalValue, goals[i].Type = utils.Round(value, 2), unit
+			}
+		}
+		schedule = append(schedule, scheduleEntry{
+			Date:         date.Format(utils.DateLayout),
+			Week:         scheduled.Week,
+			Workout:      scheduled.Workout.Name,
+			Goals:        goals,
+			Materialized: enrollment.IsMaterialized(date),
+		})
+	}
+	return c.JSON(http.StatusOK, schedule)
+}
+
+// PauseEnrollment suspends an active enrollment from today. No workouts are scheduled until
+// it is resumed, and the rest of the program then moves back by the days missed.
+func (pc *ProgramController) PauseEnrollment(c echo.Context) error {
+	enrollment, _, err := pc.findEnrollment(c)
+	if err != nil {
+		return err
+	}
+	if enrollment.Status != models.EnrollmentActive {
+		return c.JSON(http.StatusConflict, utils.ErrorResponse("Only active enrollments can be paused"))
+	}
+
+	today := utils.UserToday(c)
+	if pauseOverlaps(enrollment.Pauses, today, nil) {
+		return c.JSON(http.StatusConflict, utils.ErrorResponse("The program is already rescheduled around today"))
+	}
+	pauses := append(enrollment.Pauses, models.EnrollmentPause{Start: today})
+	return pc.saveEnrollment(c, enrollment, models.EnrollmentActive, bson.M{
+		"status": models.EnrollmentPaused,
+		"pauses": pauses,
+	})
+}
+
+// ResumeEnrollment continues a paused enrollment from today
+func (pc *ProgramController) ResumeEnrollment(c echo.Context) error {
+	enrollment, _, err := pc.findEnrollment(c)
+	if err != nil {
+		return err
+	}
+	if enrollment.Status != models.EnrollmentPaused {
+		return c.JSON(http.StatusConflict, utils.ErrorResponse("Only paused enrollments can be resumed"))
+	}
+
+	today := utils.UserToday(c)
+	pauses := make([]models.EnrollmentPause, 0, len(enrollment.Pauses))
+	for _, pause := range enrollment.Pauses {
+		if pause.End == nil {
+			// A pause lifted the day it began leaves the schedule as it was
+			if !pause.Start.Before(today) {
+				continue
+			}
+			end := today
+			pause.End = &end
+		}
+		pauses = append(pauses, pause)
+	}
+	return pc.saveEnrollment(c, enrollment, models.EnrollmentPaused, bson.M{
+		"status": models.EnrollmentActive,
+		"pauses": pauses,
+	})
+}
+
+// RescheduleEnrollment moves an active enrollment's upcoming workouts, either to a new start
+// date before any goals exist, or back by a number of days from a date
+func (pc *ProgramController) RescheduleEnrollment(c echo.Context) error {
+	enrollment, _, err := pc.findEnrollment(c)
+	if err != nil {
+		return err
+	}
+	if enrollment.Status != models.EnrollmentActive {
+		return c.JSON(http.StatusConflict, utils.ErrorResponse("Only active enrollments can be rescheduled"))
+	}
+
+	var req rescheduleRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid request body"))
+	}
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err))
+	}
+	if (req.StartDate == "") == (req.Days == 0) {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Provide either startDate or days"))
+	}
+	today := utils.UserToday(c)
+
+	if req.StartDate != "" {
+		startDate, err := utils.ResolveDate(c, req.StartDate)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid date format"))
+		}
+		if startDate.Before(today) {
+			return c.JSON(http.StatusBadRequest, utils.ErrorResponse("startDate cannot be in the past"))
+		}
+		if len(enrollment.MaterializedDates) > 0 {
+			return c.JSON(http.StatusConflict, utils.ErrorResponse("The program has started; reschedule with days instead"))
+		}
+		return pc.saveEnrollment(c, enrollment, models.EnrollmentActive, bson.M{
+			"startDate": startDate,
+			"pauses":    []models.EnrollmentPause{},
+		})
+	}
+
+	from := today
+	if req.From != "" {
+		if from, err = utils.ResolveDate(c, req.From); err != nil {
+			return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid from date"))
+		}
+	}
+	if from.Before(today) || from.Before(enrollment.StartDate) {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("from must be today or later and not before the start date"))
+	}
+	if enrollment.IsMaterialized(from) {
+		return c.JSON(http.StatusConflict, utils.ErrorResponse("Goals for that day already exist; reschedule from the next day"))
+	}
+	end := from.AddDate(0, 0, req.Days)
+	if pauseOverlaps(enrollment.Pauses, from, &end) {
+		return c.JSON(http.StatusConflict, utils.ErrorResponse("The program is already rescheduled around those days"))
+	}
+	return pc.saveEnrollment(c, enrollment, models.EnrollmentActive, bson.M{
+		"pauses": append(enrollment.Pauses, models.EnrollmentPause{Start: from, End: &end}),
+	})
+}
+
+// CancelEnrollment stops an enrollment from today. Goals already created are kept.
+func (pc *ProgramController) CancelEnrollment(c echo.Context) error {
+	enrollment, _, err := pc.findEnrollment(c)
+	if err != nil {
+		return err
+	}
+	if enrollment.Status != models.EnrollmentActive && enrollment.Status != models.EnrollmentPaused {
+		return c.JSON(http.StatusConflict, utils.ErrorResponse("Enrollment has already ended"))
+	}
+
+	// Today's workout stays if its goals were already created
+	endDate := utils.UserToday(c)
+	if enrollment.IsMaterialized(endDate) {
+		endDate = endDate.AddDate(0, 0, 1)
+	}
+	return pc.saveEnrollment(c, enrollment, enrollment.Status, bson.M{
+		"status":  models.EnrollmentCancelled,
+		"endDate": endDate,
+	})
+}
+
+// findEnrollment loads the enrollment named by :id when the authenticated user owns it,
+// along with its program, returning an *echo.HTTPError on failure. Enrollments whose last
+// workout day has passed are marked completed on the way.
+func (pc *ProgramController) findEnrollment(c echo.Context) (models.Enrollment, models.Program, error) {
+	var enrollment models.Enrollment
+	var program models.Program
+
+	userID := actorID(c)
+	if userID.IsZero() {
+		return enrollment, program, echo.NewHTTPError(http.StatusUnauthorized, utils.ErrorResponse("Unauthorized"))
+	}
+	enrollmentID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return enrollment, program, echo.NewHTTPError(http.StatusBadRequest, utils.ErrorResponse("Invalid enrollment ID"))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = pc.enrollmentCollection.FindOne(ctx, bson.M{"_id": enrollmentID, "userId": userID}).Decode(&enrollment)
+	if err == mongo.ErrNoDocuments {
+		return enrollment, program, echo.NewHTTPError(http.StatusNotFound, utils.ErrorResponse("Enrollment not found"))
+	} else if err != nil {
+		return enrollment, program, echo.NewHTTPError(http.StatusInternalServerError, utils.ErrorResponse("Failed to fetch enrollment"))
+	}
+
+	err = pc.collection.FindOne(ctx, bson.M{"_id": enrollment.ProgramID}).Decode(&program)
+	if err == mongo.ErrNoDocuments {
+		return enrollment, program, echo.NewHTTPError(http.StatusNotFound, utils.ErrorResponse("Program not found"))
+	} else if err != nil {
+		return enrollment, program, echo.NewHTTPError(http.StatusInternalServerError, utils.ErrorResponse("Failed to fetch program"))
+	}
+
+	if enrollment.Status == models.EnrollmentActive {
+		if day, ok := enrollment.ProgramDay(utils.UserToday(c)); ok && day >= program.Weeks*7 {
+			if _, err := pc.enrollmentCollection.UpdateOne(ctx,
+				bson.M{"_id": enrollment.ID, "status": models.EnrollmentActive},
+				bson.M{"$set": bson.M{"status": models.EnrollmentCompleted, "updatedAt": time.Now()}},
+			); err != nil {
+				return enrollment, program, echo.NewHTTPError(http.StatusInternalServerError, utils.ErrorResponse("Failed to update enrollment"))
+			}
+			enrollment.Status = models.EnrollmentCompleted
+		}
+	}
+	return enrollment, program, nil
+}
+
+// saveEnrollment applies changes to an enrollment still in status, answering 409 when a
+// concurrent request changed its status first
+func (pc *ProgramController) saveEnrollment(c echo.Context, enrollment models.Enrollment, status string, changes bson.M) error {
+	changes["updatedAt"] = time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var updated models.Enrollment
+	err := pc.enrollmentCollection.FindOneAndUpdate(ctx,
+		bson.M{"_id": enrollment.ID, "status": status},
+		bson.M{"$set": changes},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		return c.JSON(http.StatusConflict, utils.ErrorResponse("Enrollment was changed by another request"))
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to update enrollment"))
+	}
+	return c.JSON(http.StatusOK, updated)
+}
+
+// pauseOverlaps reports whether the days from start up to end (open-ended when nil) fall
+// inside any existing pause
+func pauseOverlaps(pauses []models.EnrollmentPause, start time.Time, end *time.Time) bool {
+	for _, pause := range pauses {
+		startsBeforeEnd := end == nil || pause.Start.Before(*end)
+		endsAfterStart := pause.End == nil || pause.End.After(start)
+		if startsBeforeEnd && endsAfterStart {
+			return true
+		}
+	}
+	return false
+}
+
+// adherence walks an enrollment's schedule and checks each workout day up to today against
+// the progress on the goals it created. Days whose goals were never created count as missed.
+func (pc *ProgramController) adherence(ctx context.Context, enrollment models.Enrollment, program models.Program, today time.Time) (adherenceReport, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"userId": enrollment.UserID, "goals.enrollmentId": enrollment.ID}}},
+		{{Key: "$unwind", Value: "$goals"}},
+		{{Key: "$match", Value: bson.M{"goals.enrollmentId": enrollment.ID}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$date",
+			"total": bson.M{"$sum": 1},
+			"done":  bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$gte": bson.A{"$goals.progressValue", "$goals.goalValue"}}, 1, 0}}},
+			"moved": bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$gt": bson.A{"$goals.progressValue", 0}}, 1, 0}}},
+		}}},
+	}
+	cursor, err := pc.dailyCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return adherenceReport{}, err
+	}
+	var days []struct {
+		Date  time.Time `bson:"_id"`
+		Total int       `bson:"total"`
+		Done  int       `bson:"done"`
+		Moved int       `bson:"moved"`
+	}
+	if err := cursor.All(ctx, &days); err != nil {
+		return adherenceReport{}, err
+	}
+	type dayProgress struct{ total, done, moved int }
+	progress := map[time.Time]dayProgress{}
+	for _, d := range days {
+		progress[d.Date.UTC()] = dayProgress{d.Total, d.Done, d.Moved}
+	}
+
+	var report adherenceReport
+	date := enrollment.StartDate.UTC()
+	for i := 0; i < 2*366; i, date = i+1, date.AddDate(0, 0, 1) {
+		if date.After(today) && enrollment.Status != models.EnrollmentActive {
+			break // Paused or ended enrollments have no predictable future
+		}
+		if enrollment.EndDate != nil && !date.Before(*enrollment.EndDate) {
+			break
+		}
+		day, ok := enrollment.ProgramDay(date)
+		if ok && day >= program.Weeks*7 {
+			break
+		}
+		if _, scheduled := workoutOn(enrollment, program, date); !scheduled {
+			continue
+		}
+		if date.After(today) {
+			report.RemainingDays++
+			continue
+		}
+
+		report.ScheduledDays++
+		p := progress[date]
+		switch {
+		case p.total > 0 && p.done == p.total:
+			report.CompletedDays++
+		case p.moved > 0 || p.done > 0:
+			report.PartialDays++
+		default:
+			report.MissedDays++
+		}
+	}
+	if report.ScheduledDays > 0 {
+		report.Rate = utils.Round(float64(report.CompletedDays)/float64(report.ScheduledDays), 3)
+	}
+	return report, nil
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"context"
+	"fitness-backend/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// programScheduler turns program enrollments into daily exercise goals. Goals are created
+// lazily, the first time a day up to today is read, so pausing or rescheduling only ever
+// moves workouts that do not exist yet.
+type programScheduler struct {
+	enrollments *mongo.Collection
+	programs    *mongo.Collection
+	daily       *mongo.Collection
+}
+
+func newProgramScheduler(db *mongo.Database) *programScheduler {
+	return &programScheduler{
+		enrollments: db.Collection("program_enrollments"),
+		programs:    db.Collection("training_programs"),
+		daily:       db.Collection("daily_data"),
+	}
+}
+
+// scheduledWorkout is the workout an enrollment schedules on a date
+type scheduledWorkout struct {
+	Week    int
+	Workout models.ProgramWorkout
+}
+
+// workoutOn returns the workout an enrollment schedules on date, if any
+func workoutOn(enrollment models.Enrollment, program models.Program, date time.Time) (scheduledWorkout, bool) {
+	if enrollment.EndDate != nil && !date.Before(*enrollment.EndDate) {
+		return scheduledWorkout{}, false
+	}
+	day, ok := enrollment.ProgramDay(date)
+	if !ok || day >= program.Weeks*7 {
+		return scheduledWorkout{}, false
+	}
+	workout, ok := program.WorkoutOn(day%7 + 1)
+	return scheduledWorkout{Week: day/7 + 1, Workout: workout}, ok
+}
+
+// goals builds the exercise goals of a scheduled workout with that week's targets
+func (sw scheduledWorkout) goals(enrollment models.Enrollment, now time.Time) []models.ExerciseGoal {
+	goals := make([]models.ExerciseGoal, 0, len(sw.Workout.Exercises))
+	for _, exercise := range sw.Workout.Exercises {
+		goal := exercise.Goal(enrollment.UserID, now)
+		goal.GoalValue = exercise.TargetFor(sw.Week)
+		goal.EnrollmentID = &enrollment.ID
+		goals = append(goals, goal)
+	}
+	return goals
+}
+
+// materialize creates the goals the user's enrollments schedule on date. Future dates are
+// left alone. Each enrollment claims a date before writing its goals, so concurrent reads
+// of the same day create them once.
+func (ps *programScheduler) materialize(ctx context.Context, userID primitive.ObjectID, date, today time.Time) error {
+	if date.After(today) {
+		return nil
+	}
+
+	cursor, err := ps.enrollments.Find(ctx, bson.M{
+		"userId":            userID,
+		"status":            bson.M{"$in": bson.A{models.EnrollmentActive, models.EnrollmentPaused, models.EnrollmentCompleted}},
+		"startDate":         bson.M{"$lte": date},
+		"materializedDates": bson.M{"$ne": date},
+	})
+	if err != nil {
+		return err
+	}
+	var enrollments []models.Enrollment
+	if err := cursor.All(ctx, &enrollments); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, enrollment := range enrollments {
+		var program models.Program
+		if err := ps.programs.FindOne(ctx, bson.M{"_id": enrollment.ProgramID}).Decode(&program); err == mongo.ErrNoDocuments {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		scheduled, ok := workoutOn(enrollment, program, date)
+		if !ok {
+			continue
+		}
+
+		claim, err := ps.enrollments.UpdateOne(ctx,
+			bson.M{"_id": enrollment.ID, "materializedDates": bson.M{"$ne": date}},
+			bson.M{"$push": bson.M{"materializedDates": date}},
+		)
+		if err != nil {
+			return err
+		}
+		if claim.ModifiedCount == 0 {
+			continue
+		}
+
+		if _, _, err := pushExerciseGoals(ctx, ps.daily, userID, date, scheduled.goals(enrollment, now)); err != nil {
+			// Release the claim so the next read retries
+			ps.enrollments.UpdateOne(ctx, bson.M{"_id": enrollment.ID}, bson.M{"$pull": bson.M{"materializedDates": date}})
+			return err
+		}
+	}
+	return nil
+}
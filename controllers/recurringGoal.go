@@ -0,0 +1,398 @@
+package controllers
+
+import (
+	"context"
+	"fitness-backend/models"
+	"fitness-backend/units"
+	"fitness-backend/utils"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RecurringGoalController manages goals repeated on a schedule
+type RecurringGoalController struct {
+	collection         *mongo.Collection
+	exerciseCollection *mongo.Collection
+	scheduler          *recurringScheduler
+}
+
+// NewRecurringGoalController initializes a new instance of RecurringGoalController
+func NewRecurringGoalController(db *mongo.Database) *RecurringGoalController {
+	return &RecurringGoalController{
+		collection:         db.Collection("recurring_goals"),
+		exerciseCollection: db.Collection("exercise_guides"),
+		scheduler:          newRecurringScheduler(db),
+	}
+}
+
+// recurringGoalRequest creates or edits a recurring goal. Kind is required on creation and
+// cannot change. Dates are "YYYY-MM-DD" or "today"; StartDate defaults to today and EndDate
+// is the first day no longer scheduled. GoalValue is in the unit named by Type.
+type recurringGoalRequest struct {
+	Kind      string `json:"kind" validate:"omitempty,oneof=exercise water calorie customgoal"`
+	StartDate string `json:"startDate"`
+	EndDate   string `json:"endDate"`
+	models.RecurringGoalVersion
+}
+
+// CreateRecurringGoal saves a recurring goal for the authenticated user. A series starting
+// today shows up in today's goals straight away.
+func (rc *RecurringGoalController) CreateRecurringGoal(c echo.Context) error {
+	userID := actorID(c)
+	if userID.IsZero() {
+		return c.JSON(http.StatusUnauthorized, utils.ErrorResponse("Unauthorized"))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := rc.bindRecurringGoal(ctx, c, userID, "")
+	if err != nil {
+		return err
+	}
+	if req.Kind == "" {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("kind is required"))
+	}
+
+	today := utils.UserToday(c)
+	startDate := today
+	if req.StartDate != "" {
+		if startDate, err = utils.ResolveDate(c, req.StartDate); err != nil {
+			return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid date format"))
+		}
+		if startDate.Before(today) {
+			return c.JSON(http.StatusBadRequest, utils.ErrorResponse("startDate cannot be in the past"))
+		}
+	}
+	endDate, err := recurringEndDate(c, req.EndDate, startDate)
+	if err != nil {
+		return err
+	}
+	if err := rc.checkNameFree(ctx, userID, primitive.NilObjectID, req.GoalName, startDate); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	version := req.RecurringGoalVersion
+	version.EffectiveFrom = startDate
+	recurring := models.RecurringGoal{
+		ID:                primitive.NewObjectID(),
+		UserID:            userID,
+		Kind:              req.Kind,
+		StartDate:         startDate,
+		EndDate:           endDate,
+		Versions:          []models.RecurringGoalVersion{version},
+		MaterializedDates: []time.Time{},
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+	if _, err := rc.collection.InsertOne(ctx, recurring); err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to create recurring goal"))
+	}
+
+	if err := rc.scheduler.materialize(ctx, userID, today, today); err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to schedule today's goal"))
+	}
+	return c.JSON(http.StatusCreated, localizeRecurringGoal(recurring, utils.UserUnits(c)))
+}
+
+// GetRecurringGoals lists the authenticated user's recurring goals, newest first.
+// ?active=true leaves out series that have ended.
+func (rc *RecurringGoalController) GetRecurringGoals(c echo.Context) error {
+	userID := actorID(c)
+	if userID.IsZero() {
+		return c.JSON(http.StatusUnauthorized, utils.ErrorResponse("Unauthorized"))
+	}
+
+	filter := bson.M{"userId": userID}
+	if c.QueryParam("active") == "true" {
+		filter["$or"] = bson.A{bson.M{"endDate": nil}, bson.M{"endDate": bson.M{"$gt": utils.UserToday(c)}}}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := rc.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to fetch recurring goals"))
+	}
+	var series []models.RecurringGoal
+	if err := cursor.All(ctx, &series); err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error decoding recurring goals"))
+	}
+
+	system := utils.UserUnits(c)
+	response := make([]models.RecurringGoal, 0, len(series))
+	for _, recurring := range series {
+		response = append(response, localizeRecurringGoal(recurring, system))
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// GetRecurringGoal returns one of the authenticated user's recurring goals
+func (rc *RecurringGoalController) GetRecurringGoal(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	recurring, err := rc.findRecurringGoal(ctx, c)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, localizeRecurringGoal(recurring, utils.UserUnits(c)))
+}
+
+// UpdateRecurringGoal replaces the schedule and goal of a series from tomorrow on. Days up
+// to today keep the goal they had, whether or not it has been created yet. A series that
+// has not started is edited as a whole and may also move its startDate.
+func (rc *RecurringGoalController) UpdateRecurringGoal(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	recurring, err := rc.findRecurringGoal(ctx, c)
+	if err != nil {
+		return err
+	}
+	req, err := rc.bindRecurringGoal(ctx, c, recurring.UserID, recurring.Kind)
+	if err != nil {
+		return err
+	}
+	if req.Kind != "" && req.Kind != recurring.Kind {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("kind cannot be changed"))
+	}
+
+	today := utils.UserToday(c)
+	tomorrow := today.AddDate(0, 0, 1)
+	startDate := recurring.StartDate
+	version := req.RecurringGoalVersion
+	var versions []models.RecurringGoalVersion
+	if recurring.StartDate.After(today) {
+		if req.StartDate != "" {
+			if startDate, err = utils.ResolveDate(c, req.StartDate); err != nil {
+				return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid date format"))
+			}
+			if startDate.Before(today) {
+				return c.JSON(http.StatusBadRequest, utils.ErrorResponse("startDate cannot be in the past"))
+			}
+		}
+		version.EffectiveFrom = startDate
+		versions = []models.RecurringGoalVersion{version}
+	} else {
+		if req.StartDate != "" {
+			return c.JSON(http.StatusBadRequest, utils.ErrorResponse("startDate cannot change once the series has started"))
+		}
+		if recurring.EndDate != nil && !recurring.EndDate.After(tomorrow) {
+			return c.JSON(http.StatusConflict, utils.ErrorResponse("This recurring goal has ended"))
+		}
+		for _, v := range recurring.Versions {
+			if v.EffectiveFrom.Before(tomorrow) {
+				versions = append(versions, v)
+			}
+		}
+		version.EffectiveFrom = tomorrow
+		// Keeping the interval keeps its phase, so editing the goal alone does not move the days
+		if previous := versions[len(versions)-1]; version.Frequency == models.RecurInterval &&
+			previous.Frequency == models.RecurInterval && previous.Interval == version.Interval {
+			version.Anchor = previous.Anchor
+			if version.Anchor == nil {
+				version.Anchor = &previous.EffectiveFrom
+			}
+		}
+		versions = append(versions, version)
+	}
+
+	endDate := recurring.EndDate
+	if req.EndDate != "" {
+		if endDate, err = recurringEndDate(c, req.EndDate, version.EffectiveFrom); err != nil {
+			return err
+		}
+	}
+	if err := rc.checkNameFree(ctx, recurring.UserID, recurring.ID, version.GoalName, version.EffectiveFrom); err != nil {
+		return err
+	}
+
+	// Matching updatedAt keeps concurrent edits from overwriting each other's versions
+	var updated models.RecurringGoal
+	err = rc.collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": recurring.ID, "updatedAt": recurring.UpdatedAt},
+		bson.M{"$set": bson.M{
+			"startDate": startDate,
+			"endDate":   endDate,
+			"versions":  versions,
+			"updatedAt": time.Now(),
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		return c.JSON(http.StatusConflict, utils.ErrorResponse("Recurring goal changed; fetch it again"))
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to update recurring goal"))
+	}
+	return c.JSON(http.StatusOK, localizeRecurringGoal(updated, utils.UserUnits(c)))
+}
+
+// DeleteRecurringGoal stops a series from tomorrow on. Goals it already created are kept;
+// a series that has not started is removed.
+func (rc *RecurringGoalController) DeleteRecurringGoal(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	recurring, err := rc.findRecurringGoal(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	today := utils.UserToday(c)
+	if recurring.StartDate.After(today) {
+		if _, err := rc.collection.DeleteOne(ctx, bson.M{"_id": recurring.ID}); err != nil {
+			return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to delete recurring goal"))
+		}
+		return c.JSON(http.StatusOK, utils.SuccessResponse("Recurring goal deleted successfully"))
+	}
+
+	tomorrow := today.AddDate(0, 0, 1)
+	_, err = rc.collection.UpdateOne(ctx,
+		bson.M{"_id": recurring.ID, "$or": bson.A{bson.M{"endDate": nil}, bson.M{"endDate": bson.M{"$gt": tomorrow}}}},
+		bson.M{"$set": bson.M{"endDate": tomorrow, "updatedAt": time.Now()}},
+	)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to stop recurring goal"))
+	}
+	return c.JSON(http.StatusOK, utils.SuccessResponse("Recurring goal stopped; goals already created are kept"))
+}
+
+// findRecurringGoal loads the recurring goal named by :id if it belongs to the
+// authenticated user, returning an *echo.HTTPError otherwise
+func (rc *RecurringGoalController) findRecurringGoal(ctx context.Context, c echo.Context) (models.RecurringGoal, error) {
+	var recurring models.RecurringGoal
+	userID := actorID(c)
+	if userID.IsZero() {
+		return recurring, echo.NewHTTPError(http.StatusUnauthorized, utils.ErrorResponse("Unauthorized"))
+	}
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return recurring, echo.NewHTTPError(http.StatusBadRequest, utils.ErrorResponse("Invalid recurring goal ID"))
+	}
+
+	err = rc.collection.FindOne(ctx, bson.M{"_id": id, "userId": userID}).Decode(&recurring)
+	if err == mongo.ErrNoDocuments {
+		return recurring, echo.NewHTTPError(http.StatusNotFound, utils.ErrorResponse("Recurring goal not found"))
+	} else if err != nil {
+		return recurring, echo.NewHTTPError(http.StatusInternalServerError, utils.ErrorResponse("Failed to fetch recurring goal"))
+	}
+	return recurring, nil
+}
+
+// bindRecurringGoal reads and validates a recurring goal request, storing the goal value in
+// canonical units and linking exercise goals to their exercise. kind is the kind of the
+// series being edited, or empty on creation.
+func (rc *RecurringGoalController) bindRecurringGoal(ctx context.Context, c echo.Context, userID primitive.ObjectID, kind string) (recurringGoalRequest, error) {
+	var req recurringGoalRequest
+	if err := c.Bind(&req); err != nil {
+		return req, echo.NewHTTPError(http.StatusBadRequest, utils.ErrorResponse("Invalid request body"))
+	}
+	if kind == "" {
+		kind = req.Kind
+	}
+
+	v := &req.RecurringGoalVersion
+	v.Anchor = nil
+	switch v.Frequency {
+	case models.RecurDaily:
+		v.Weekdays, v.Interval = nil, 0
+	case models.RecurWeekdays:
+		if len(v.Weekdays) == 0 {
+			return req, echo.NewHTTPError(http.StatusBadRequest, utils.ErrorResponse("weekdays is required for a weekdays schedule"))
+		}
+		v.Interval = 0
+	case models.RecurInterval:
+		if v.Interval < 1 {
+			return req, echo.NewHTTPError(http.StatusBadRequest, utils.ErrorResponse("interval is required for an interval schedule"))
+		}
+		v.Weekdays = nil
+	}
+	v.GoalValue, v.Type = units.ToCanonical(v.GoalValue, v.Type)
+
+	if kind == "exercise" {
+		if v.Type != "reps" && v.Type != "mins" && v.Type != "kms" {
+			return req, echo.NewHTTPError(http.StatusBadRequest, utils.ErrorResponse("Exercise goals are counted in reps, mins or a distance"))
+		}
+		if !v.ExerciseID.IsZero() {
+			var exercise models.Exercise
+			err := rc.exerciseCollection.FindOne(ctx, bson.M{"$and": bson.A{bson.M{"_id": v.ExerciseID, "deletedAt": nil}, exerciseVisibility(userID)}}).Decode(&exercise)
+			if err == mongo.ErrNoDocuments {
+				return req, echo.NewHTTPError(http.StatusBadRequest, utils.ErrorResponse("Exercise not found"))
+			} else if err != nil {
+				return req, echo.NewHTTPError(http.StatusInternalServerError, utils.ErrorResponse("Failed to fetch exercise"))
+			}
+			if v.GoalName == "" {
+				v.GoalName = exercise.Name
+			}
+		} else if v.GoalName != "" {
+			exercise, err := findExerciseForUser(ctx, rc.exerciseCollection, userID, v.GoalName)
+			if err != nil && err != mongo.ErrNoDocuments {
+				return req, echo.NewHTTPError(http.StatusInternalServerError, utils.ErrorResponse("Failed to fetch exercise"))
+			}
+			v.ExerciseID = exercise.ID
+		}
+	} else {
+		v.ExerciseID, v.Intensity = primitive.NilObjectID, ""
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return req, echo.NewHTTPError(http.StatusBadRequest, utils.ValidationErrorResponse(err))
+	}
+	return req, nil
+}
+
+// checkNameFree returns an *echo.HTTPError when another of the user's series still running
+// on or after from uses goalName, since a day holds one goal of each name
+func (rc *RecurringGoalController) checkNameFree(ctx context.Context, userID, exclude primitive.ObjectID, goalName string, from time.Time) error {
+	count, err := rc.collection.CountDocuments(ctx, bson.M{
+		"_id":               bson.M{"$ne": exclude},
+		"userId":            userID,
+		"versions.goalName": goalName,
+		"$or":               bson.A{bson.M{"endDate": nil}, bson.M{"endDate": bson.M{"$gt": from}}},
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, utils.ErrorResponse("Failed to check recurring goals"))
+	}
+	if count > 0 {
+		return echo.NewHTTPError(http.StatusConflict, utils.ErrorResponse("Another recurring goal is already named "+goalName))
+	}
+	return nil
+}
+
+// recurringEndDate parses an optional end date, which must come after from and today
+func recurringEndDate(c echo.Context, value string, from time.Time) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	endDate, err := utils.ResolveDate(c, value)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, utils.ErrorResponse("Invalid date format"))
+	}
+	if !endDate.After(from) || !endDate.After(utils.UserToday(c)) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, utils.ErrorResponse("endDate must come after the start of the schedule"))
+	}
+	return &endDate, nil
+}
+
+// localizeRecurringGoal converts the goal values of a series into the user's unit system
+func localizeRecurringGoal(recurring models.RecurringGoal, system string) models.RecurringGoal {
+	versions := make([]models.RecurringGoalVersion, len(recurring.Versions))
+	for i, v := range recurring.Versions {
+		if units.IsConvertible(v.Type) {
+			value, unit := units.ToDisplay(v.GoalValue, v.Type, system)
+			v.GoalValue, v.Type = utils.Round(value, 2), unit
+		}
+		versions[i] = v
+	}
+	recurring.Versions = versions
+	return recurring
+}
@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"context"
+	"fitness-backend/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// recurringScheduler turns recurring goals into daily goals. Like program workouts, goals
+// are created lazily, the first time a day up to today is read or written, so an edit to a
+// series reaches every day that does not have its goal yet.
+type recurringScheduler struct {
+	recurring *mongo.Collection
+	daily     *mongo.Collection
+}
+
+func newRecurringScheduler(db *mongo.Database) *recurringScheduler {
+	return &recurringScheduler{
+		recurring: db.Collection("recurring_goals"),
+		daily:     db.Collection("daily_data"),
+	}
+}
+
+// materialize creates the goals the user's recurring goals schedule on date. Future dates
+// are left alone. Each series claims a date before writing its goal, so concurrent requests
+// for the same day create it once. A day that already has a goal of the same name keeps it.
+func (rs *recurringScheduler) materialize(ctx context.Context, userID primitive.ObjectID, date, today time.Time) error {
+	if date.After(today) {
+		return nil
+	}
+
+	cursor, err := rs.recurring.Find(ctx, bson.M{
+		"userId":            userID,
+		"startDate":         bson.M{"$lte": date},
+		"$or":               bson.A{bson.M{"endDate": nil}, bson.M{"endDate": bson.M{"$gt": date}}},
+		"materializedDates": bson.M{"$ne": date},
+	})
+	if err != nil {
+		return err
+	}
+	var series []models.RecurringGoal
+	if err := cursor.All(ctx, &series); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, recurring := range series {
+		version, ok := recurring.OccursOn(date)
+		if !ok {
+			continue
+		}
+
+		claim, err := rs.recurring.UpdateOne(ctx,
+			bson.M{"_id": recurring.ID, "materializedDates": bson.M{"$ne": date}},
+			bson.M{"$push": bson.M{"materializedDates": date}},
+		)
+		if err != nil {
+			return err
+		}
+		if claim.ModifiedCount == 0 {
+			continue
+		}
+
+		goals := []interface{}{version.Goal(recurring.Kind, userID, now)}
+		if _, _, err := pushGoals(ctx, rs.daily, userID, date, goals, func(interface{}) string { return version.GoalName }); err != nil {
+			// Release the claim so the next request retries
+			rs.recurring.UpdateOne(ctx, bson.M{"_id": recurring.ID}, bson.M{"$pull": bson.M{"materializedDates": date}})
+			return err
+		}
+	}
+	return nil
+}
+
+// materializeDay creates the goals that program enrollments and recurring goals schedule
+// on date, before it is read or written
+func materializeDay(ctx context.Context, programs *programScheduler, recurring *recurringScheduler, userID primitive.ObjectID, date, today time.Time) error {
+	if err := programs.materialize(ctx, userID, date, today); err != nil {
+		return err
+	}
+	return recurring.materialize(ctx, userID, date, today)
+}
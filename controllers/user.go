@@ -0,0 +1,222 @@
+package controllers
+
+import (
+	"context"
+	"fitness-backend/models"
+	"fitness-backend/units"
+	"fitness-backend/utils"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type UserController struct {
+	collection        *mongo.Collection
+	historyCollection *mongo.Collection
+	dailyCollection   *mongo.Collection
+}
+
+// NewUserController initializes a new instance of UserController
+func NewUserController(db *mongo.Database) *UserController {
+	return &UserController{
+		collection:        db.Collection("users"),
+		historyCollection: db.Collection("profile_history"),
+		dailyCollection:   db.Collection("daily_data"),
+	}
+}
+
+// profileUpdateRequest lists the profile fields a user may change; nil fields are left untouched
+type profileUpdateRequest struct {
+	Name          *string  `json:"name" validate:"omitnil,min=1,max=100"`
+	Weight        *float64 `json:"weight" validate:"omitnil,gt=0,lte=500"`
+	Height        *float64 `json:"height" validate:"omitnil,gt=0,lte=300"`
+	BirthDate     *string  `json:"birthDate" validate:"omitnil,datetime=2006-01-02"`
+	Sex           *string  `json:"sex" validate:"omitnil,oneof=male female other"`
+	BodyFat       *float64 `json:"bodyFat" validate:"omitnil,gte=2,lte=70"`
+	ActivityLevel *string  `json:"activityLevel" validate:"omitnil,oneof=sedentary light moderate active very_active"`
+	Units         *string  `json:"units" validate:"omitnil,oneof=metric imperial"`
+	Timezone      *string  `json:"timezone" validate:"omitnil,timezone"`
+}
+
+// UpdateProfile applies a partial update to the authenticated user's profile.
+// Weight and height changes are also appended to the profile history.
+// New units or timezone apply to writes straight away and reach the access token claims,
+// which localize reads, on the next refresh.
+func (uc *UserController) UpdateProfile(c echo.Context) error {
+	userIDString, ok := c.Get("user_id").(string)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, utils.ErrorResponse("Unauthorized"))
+	}
+	userID, err := primitive.ObjectIDFromHex(userIDString)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid user ID"))
+	}
+
+	var req profileUpdateRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid request body"))
+	}
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err))
+	}
+
+	ctx := c.Request().Context()
+	var current models.User
+	err = uc.collection.FindOne(ctx, bson.M{"_id": userID}).Decode(&current)
+	if err == mongo.ErrNoDocuments {
+		return c.JSON(http.StatusNotFound, utils.ErrorResponse("User not found"))
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error fetching user"))
+	}
+
+	now := time.Now()
+	set := bson.M{"updatedAt": now}
+	if req.Name != nil {
+		set["name"] = *req.Name
+	}
+	if req.Weight != nil {
+		set["weight"] = *req.Weight
+	}
+	if req.Height != nil {
+		set["height"] = *req.Height
+	}
+	if req.BirthDate != nil {
+		birthDate, _ := time.Parse(utils.DateLayout, *req.BirthDate)
+		if !birthDate.Before(now) {
+			return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(utils.ValidationErrors{
+				{Field: "birthDate", Rule: "past", Message: "must be in the past"},
+			}))
+		}
+		set["birthDate"] = birthDate
+	}
+	if req.Sex != nil {
+		set["sex"] = *req.Sex
+	}
+	if req.BodyFat != nil {
+		set["bodyFat"] = *req.BodyFat
+	}
+	if req.ActivityLevel != nil {
+		set["activityLevel"] = *req.ActivityLevel
+	}
+	if req.Units != nil {
+		set["units"] = *req.Units
+	}
+	if req.Timezone != nil {
+		set["timezone"] = *req.Timezone
+	}
+
+	var history []interface{}
+	if req.Weight != nil && *req.Weight != current.Weight {
+		history = append(history, models.ProfileHistoryEntry{
+			ID: primitive.NewObjectID(), UserID: userID, Field: "weight",
+			Value: *req.Weight, PreviousValue: current.Weight, RecordedAt: now,
+		})
+	}
+	if req.Height != nil && *req.Height != current.Height {
+		history = append(history, models.ProfileHistoryEntry{
+			ID: primitive.NewObjectID(), UserID: userID, Field: "height",
+			Value: *req.Height, PreviousValue: current.Height, RecordedAt: now,
+		})
+	}
+	if len(history) > 0 {
+		if _, err := uc.historyCollection.InsertMany(ctx, history); err != nil {
+			return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error recording profile history"))
+		}
+	}
+
+	// Switching unit systems is when weight goals still stored in pounds get normalized,
+	// so they display correctly in the new system
+	if req.Units != nil && *req.Units != current.Units {
+		if err := uc.normalizeWeightGoals(ctx, userID); err != nil {
+			return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error converting weight goals"))
+		}
+	}
+
+	var updated models.User
+	err = uc.collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": userID},
+		bson.M{"$set": set},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error updating profile"))
+	}
+
+	// Remove sensitive information
+	updated.Password = ""
+	updated.Role = updated.EffectiveRole()
+
+	return c.JSON(http.StatusOK, updated)
+}
+
+// normalizeWeightGoals converts the user's weight goals recorded in pounds to kilograms
+func (uc *UserController) normalizeWeightGoals(ctx context.Context, userID primitive.ObjectID) error {
+	toKg := func(expr string) bson.M {
+		return bson.M{"$multiply": bson.A{expr, units.Convert(1, units.Pound, units.Kilogram)}}
+	}
+	converted := bson.M{"$mergeObjects": bson.A{"$$g", bson.M{
+		"unit":         units.Kilogram,
+		"goalValue":    toKg("$$g.goalValue"),
+		"currentValue": toKg("$$g.currentValue"),
+		"entries": bson.M{"$map": bson.M{
+			"input": bson.M{"$ifNull": bson.A{"$$g.entries", bson.A{}}},
+			"as":    "e",
+			"in":    bson.M{"$mergeObjects": bson.A{"$$e", bson.M{"value": toKg("$$e.value")}}},
+		}},
+	}}}
+
+	pipeline := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"goals": bson.M{"$map": bson.M{
+			"input": "$goals",
+			"as":    "g",
+			"in": bson.M{"$cond": bson.A{
+				bson.M{"$eq": bson.A{"$$g.unit", units.Pound}},
+				converted,
+				"$$g",
+			}},
+		}},
+	}}}}
+
+	_, err := uc.dailyCollection.UpdateMany(ctx, bson.M{"userId": userID, "goals.unit": units.Pound}, pipeline)
+	return err
+}
+
+// GetProfileHistory returns the recorded weight and height changes, newest first.
+// Pass ?field=weight or ?field=height to narrow the list.
+func (uc *UserController) GetProfileHistory(c echo.Context) error {
+	userIDString, ok := c.Get("user_id").(string)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, utils.ErrorResponse("Unauthorized"))
+	}
+	userID, err := primitive.ObjectIDFromHex(userIDString)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid user ID"))
+	}
+
+	filter := bson.M{"userId": userID}
+	if field := c.QueryParam("field"); field != "" {
+		if field != "weight" && field != "height" {
+			return c.JSON(http.StatusBadRequest, utils.ErrorResponse("field must be weight or height"))
+		}
+		filter["field"] = field
+	}
+
+	ctx := c.Request().Context()
+	cursor, err := uc.historyCollection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "recordedAt", Value: -1}}))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error fetching profile history"))
+	}
+	defer cursor.Close(ctx)
+
+	history := make([]models.ProfileHistoryEntry, 0)
+	if err := cursor.All(ctx, &history); err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error decoding profile history"))
+	}
+
+	return c.JSON(http.StatusOK, history)
+}
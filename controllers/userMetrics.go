@@ -0,0 +1,194 @@
+package controllers
+
+import (
+	"context"
+	"fitness-backend/models"
+	"fitness-backend/utils"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// calorieGoalName is the goalName of the daily calorie NutritionGoal
+const calorieGoalName = "calories"
+
+// bodyMetrics is the server-side calculation of a user's energy needs
+type bodyMetrics struct {
+	BMI           float64            `json:"bmi"`
+	BMICategory   string             `json:"bmiCategory"`
+	BMR           map[string]float64 `json:"bmr"`           // kcal/day by formula
+	BMRFormula    string             `json:"bmrFormula"`    // Formula used for the TDEE
+	ActivityLevel string             `json:"activityLevel"` // Level used for the TDEE
+	TDEE          float64            `json:"tdee"`          // kcal/day
+}
+
+// GetMetrics returns BMI, BMR and TDEE calculated from the user's profile
+func (uc *UserController) GetMetrics(c echo.Context) error {
+	user, err := uc.currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	metrics, missing := calculateBodyMetrics(user, time.Now())
+	if len(missing) > 0 {
+		return c.JSON(http.StatusUnprocessableEntity, echo.Map{"error": "Profile is incomplete", "missing": missing})
+	}
+
+	return c.JSON(http.StatusOK, metrics)
+}
+
+// ApplyCalorieGoal creates or updates the day's calorie NutritionGoal from the user's TDEE.
+// An optional adjustment (e.g. -500 for a deficit) is added to the TDEE.
+func (uc *UserController) ApplyCalorieGoal(c echo.Context) error {
+	user, err := uc.currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	date, err := utils.ResolveDate(c, c.Param("date"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid date format"))
+	}
+
+	var req struct {
+		Adjustment float64 `json:"adjustment" validate:"gte=-2000,lte=2000"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid request body"))
+	}
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err))
+	}
+
+	metrics, missing := calculateBodyMetrics(user, time.Now())
+	if len(missing) > 0 {
+		return c.JSON(http.StatusUnprocessableEntity, echo.Map{"error": "Profile is incomplete", "missing": missing})
+	}
+
+	target := utils.Round(metrics.TDEE+req.Adjustment, 0)
+	if target < 0 {
+		target = 0
+	}
+
+	goal, created, err := uc.upsertCalorieGoal(c.Request().Context(), user.ID, date, target)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to save calorie goal"))
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	return c.JSON(status, echo.Map{"metrics": metrics, "goal": goal})
+}
+
+// upsertCalorieGoal sets the goalValue of the calorie goal on a date, creating the goal if needed
+func (uc *UserController) upsertCalorieGoal(ctx context.Context, userID primitive.ObjectID, date time.Time, target float64) (interface{}, bool, error) {
+	now := time.Now()
+	filter := bson.M{"userId": userID, "date": date, "goals.goalName": calorieGoalName}
+	var updated models.DailyDataCollection
+	err := uc.dailyCollection.FindOneAndUpdate(ctx, filter,
+		bson.M{"$set": bson.M{"goals.$.goalValue": target, "goals.$.updatedAt": now}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err == nil {
+		for _, goal := range updated.Goals {
+			if d, ok := goal.(primitive.D); ok && d.Map()["goalName"] == calorieGoalName {
+				return d.Map(), false, nil
+			}
+		}
+		return nil, false, nil
+	} else if err != mongo.ErrNoDocuments {
+		return nil, false, err
+	}
+
+	goal := models.NutritionGoal{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		GoalName:  calorieGoalName,
+		Type:      "kcal",
+		GoalValue: target,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	_, err = uc.dailyCollection.UpdateOne(ctx,
+		bson.M{"userId": userID, "date": date},
+		bson.M{"$push": bson.M{"goals": goal}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return nil, false, err
+	}
+	return goal, true, nil
+}
+
+// currentUser loads the authenticated user, returning an *echo.HTTPError on failure
+func (uc *UserController) currentUser(c echo.Context) (models.User, error) {
+	userIDString, ok := c.Get("user_id").(string)
+	if !ok {
+		return models.User{}, echo.NewHTTPError(http.StatusUnauthorized, utils.ErrorResponse("Unauthorized"))
+	}
+	userID, err := primitive.ObjectIDFromHex(userIDString)
+	if err != nil {
+		return models.User{}, echo.NewHTTPError(http.StatusBadRequest, utils.ErrorResponse("Invalid user ID"))
+	}
+
+	var user models.User
+	err = uc.collection.FindOne(c.Request().Context(), bson.M{"_id": userID}).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return models.User{}, echo.NewHTTPError(http.StatusNotFound, utils.ErrorResponse("User not found"))
+	} else if err != nil {
+		return models.User{}, echo.NewHTTPError(http.StatusInternalServerError, utils.ErrorResponse("Error fetching user"))
+	}
+	return user, nil
+}
+
+// calculateBodyMetrics computes BMI, BMR and TDEE. Katch-McArdle is preferred when body fat is known.
+// It returns the profile fields that are missing for the calculation.
+func calculateBodyMetrics(user models.User, now time.Time) (bodyMetrics, []string) {
+	var missing []string
+	if user.Weight <= 0 {
+		missing = append(missing, "weight")
+	}
+	if user.Height <= 0 {
+		missing = append(missing, "height")
+	}
+	if user.BirthDate == nil {
+		missing = append(missing, "birthDate")
+	}
+	if user.Sex == "" {
+		missing = append(missing, "sex")
+	}
+	if user.ActivityLevel == "" {
+		missing = append(missing, "activityLevel")
+	}
+	if len(missing) > 0 {
+		return bodyMetrics{}, missing
+	}
+
+	bmi := utils.BMI(user.Weight, user.Height)
+	metrics := bodyMetrics{
+		BMI:           utils.Round(bmi, 1),
+		BMICategory:   utils.BMICategory(bmi),
+		BMR:           map[string]float64{},
+		ActivityLevel: user.ActivityLevel,
+	}
+
+	bmr := utils.BMRMifflinStJeor(user.Weight, user.Height, utils.AgeOn(*user.BirthDate, now), user.Sex)
+	metrics.BMR["mifflinStJeor"] = utils.Round(bmr, 0)
+	metrics.BMRFormula = "mifflinStJeor"
+	if user.BodyFat > 0 {
+		bmr = utils.BMRKatchMcArdle(user.Weight, user.BodyFat)
+		metrics.BMR["katchMcArdle"] = utils.Round(bmr, 0)
+		metrics.BMRFormula = "katchMcArdle"
+	}
+
+	tdee, _ := utils.TDEE(bmr, user.ActivityLevel)
+	metrics.TDEE = utils.Round(tdee, 0)
+	return metrics, nil
+}
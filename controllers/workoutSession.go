@@ -0,0 +1,553 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fitness-backend/models"
+	"fitness-backend/units"
+	"fitness-backend/utils"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxSessionHistory caps the sessions listed at once
+const maxSessionHistory = 50
+
+type SessionController struct {
+	collection         *mongo.Collection
+	dailyCollection    *mongo.Collection
+	exerciseCollection *mongo.Collection
+	records            *recordKeeper
+}
+
+// NewSessionController initializes a new instance of SessionController
+func NewSessionController(db *mongo.Database) *SessionController {
+	return &SessionController{
+		collection:         db.Collection("workout_sessions"),
+		dailyCollection:    db.Collection("daily_data"),
+		exerciseCollection: db.Collection("exercise_guides"),
+		records:            newRecordKeeper(db),
+	}
+}
+
+// startSessionRequest starts a session; Date is "YYYY-MM-DD" or "today" and defaults to today
+type startSessionRequest struct {
+	Name string `json:"name" validate:"max=100"`
+	Date string `json:"date"`
+}
+
+// StartSession starts timing a workout. A user has at most one active session; starting
+// another answers 409 with the active one so the client can resume it.
+func (sc *SessionController) StartSession(c echo.Context) error {
+	userID := actorID(c)
+	if userID.IsZero() {
+		return c.JSON(http.StatusUnauthorized, utils.ErrorResponse("Unauthorized"))
+	}
+
+	var req startSessionRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid request body"))
+	}
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err))
+	}
+	date := utils.UserToday(c)
+	if req.Date != "" {
+		var err error
+		if date, err = utils.ResolveDate(c, req.Date); err != nil {
+			return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid date format"))
+		}
+	}
+
+	now := time.Now()
+	session := models.WorkoutSession{
+		ID:             primitive.NewObjectID(),
+		UserID:         userID,
+		Name:           req.Name,
+		Date:           date,
+		Status:         models.SessionActive,
+		Exercises:      []models.SessionExercise{},
+		Rests:          []models.SessionRest{},
+		StartedAt:      now,
+		LastActivityAt: now,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := sc.collection.InsertOne(ctx, session); mongo.IsDuplicateKeyError(err) {
+		var active models.WorkoutSession
+		if err := sc.collection.FindOne(ctx, bson.M{"userId": userID, "status": models.SessionActive}).Decode(&active); err != nil {
+			return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to fetch active session"))
+		}
+		return c.JSON(http.StatusConflict, echo.Map{
+			"error":   "A session is already in progress",
+			"session": localizeSession(active, utils.UserUnits(c)),
+		})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to start session"))
+	}
+	return c.JSON(http.StatusCreated, localizeSession(session, utils.UserUnits(c)))
+}
+
+// GetActiveSession returns the session in progress with the time elapsed so far, so a client
+// that lost its connection can pick up where it left off
+func (sc *SessionController) GetActiveSession(c echo.Context) error {
+	userID := actorID(c)
+	if userID.IsZero() {
+		return c.JSON(http.StatusUnauthorized, utils.ErrorResponse("Unauthorized"))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var session models.WorkoutSession
+	err := sc.collection.FindOne(ctx, bson.M{"userId": userID, "status": models.SessionActive}).Decode(&session)
+	if err == mongo.ErrNoDocuments {
+		return c.JSON(http.StatusNotFound, utils.ErrorResponse("No session in progress"))
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to fetch session"))
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"session":        localizeSession(session, utils.UserUnits(c)),
+		"elapsedSeconds": int(time.Since(session.StartedAt).Seconds()),
+	})
+}
+
+// GetSessions lists the authenticated user's sessions, newest first. Supports ?status=.
+func (sc *SessionController) GetSessions(c echo.Context) error {
+	userID := actorID(c)
+	if userID.IsZero() {
+		return c.JSON(http.StatusUnauthorized, utils.ErrorResponse("Unauthorized"))
+	}
+
+	filter := bson.M{"userId": userID}
+	if status := c.QueryParam("status"); status != "" {
+		filter["status"] = status
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "startedAt", Value: -1}}).SetLimit(maxSessionHistory)
+	cursor, err := sc.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to fetch sessions"))
+	}
+	sessions := make([]models.WorkoutSession, 0)
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error decoding sessions"))
+	}
+
+	system := utils.UserUnits(c)
+	for i := range sessions {
+		sessions[i] = localizeSession(sessions[i], system)
+	}
+	return c.JSON(http.StatusOK, sessions)
+}
+
+// GetSession returns one of the authenticated user's sessions
+func (sc *SessionController) GetSession(c echo.Context) error {
+	session, err := sc.findSession(c)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, localizeSession(session, utils.UserUnits(c)))
+}
+
+// AddSessionExercise starts an exercise in an active session. The exercise is linked by
+// exerciseId or, failing that, by goalName. A client may send its own id so that retrying
+// after a lost response does not add the exercise twice.
+func (sc *SessionController) AddSessionExercise(c echo.Context) error {
+	userID := actorID(c)
+	var entry models.SessionExercise
+	if err := c.Bind(&entry); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid request body"))
+	}
+	_, entry.Type = units.ToCanonical(0, entry.Type)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if !entry.ExerciseID.IsZero() {
+		var exercise models.Exercise
+		filter := bson.M{"$and": bson.A{bson.M{"_id": entry.ExerciseID, "deletedAt": nil}, exerciseVisibility(userID)}}
+		if err := sc.exerciseCollection.FindOne(ctx, filter).Decode(&exercise); err == mongo.ErrNoDocuments {
+			return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Exercise not found"))
+		} else if err != nil {
+			return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to fetch exercise"))
+		}
+		if entry.GoalName == "" {
+			entry.GoalName = exercise.Name
+		}
+	} else if entry.GoalName != "" {
+		exercise, err := findExerciseForUser(ctx, sc.exerciseCollection, userID, entry.GoalName)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to fetch exercise"))
+		}
+		entry.ExerciseID = exercise.ID
+	}
+
+	// The sets end up in the day's goal of the same name, which has to be able to hold them
+	session, err := sc.findSession(c)
+	if err != nil {
+		return err
+	}
+	var dailyData models.DailyDataCollection
+	err = sc.dailyCollection.FindOne(ctx, bson.M{"userId": session.UserID, "date": session.Date}).Decode(&dailyData)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error retrieving goals"))
+	}
+	existing := findGoalByName(dailyData.Goals, entry.GoalName)
+	if goalType, ok := existing["type"].(string); ok && entry.Type == "" {
+		entry.Type = goalType
+	}
+	if reason := sessionGoalConflict(existing, entry.Type); reason != "" {
+		return c.JSON(http.StatusConflict, utils.ErrorResponse(reason))
+	}
+
+	if err := c.Validate(&entry); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err))
+	}
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+	entry.Sets = []models.ExerciseSet{}
+	entry.AddedAt = time.Now()
+
+	return sc.logToSession(c, http.StatusCreated,
+		bson.M{"exercises._id": bson.M{"$ne": entry.ID}, "exercises.goalName": bson.M{"$ne": entry.GoalName}},
+		bson.M{"$push": bson.M{"exercises": entry}},
+		nil,
+		func(session models.WorkoutSession) error {
+			for _, existing := range session.Exercises {
+				if existing.ID == entry.ID {
+					return nil
+				}
+				if existing.GoalName == entry.GoalName {
+					return echo.NewHTTPError(http.StatusConflict, echo.Map{"error": "The session already has this exercise; log sets to it", "exerciseId": existing.ID})
+				}
+			}
+			return nil
+		},
+	)
+}
+
+// LogSessionSet records a set of an exercise in an active session. Weight and distance are
+// in the user's units. A client may send the set's id so that a retried request is ignored.
+func (sc *SessionController) LogSessionSet(c echo.Context) error {
+	entryID, err := primitive.ObjectIDFromHex(c.Param("exerciseId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid exercise ID"))
+	}
+	set, err := bindSet(c)
+	if err != nil {
+		return err
+	}
+	if set.ID.IsZero() {
+		set.ID = primitive.NewObjectID()
+	}
+	set.LoggedAt = time.Now()
+
+	return sc.logToSession(c, http.StatusCreated,
+		bson.M{"exercises._id": entryID, "exercises.sets._id": bson.M{"$ne": set.ID}},
+		bson.M{"$push": bson.M{"exercises.$[e].sets": set}},
+		options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{bson.M{"e._id": entryID}}}),
+		func(session models.WorkoutSession) error {
+			for _, existing := range session.Exercises {
+				if existing.ID == entryID {
+					return nil
+				}
+			}
+			return echo.NewHTTPError(http.StatusNotFound, utils.ErrorResponse("Exercise not found in session"))
+		},
+	)
+}
+
+// LogSessionRest records a rest period taken in an active session
+func (sc *SessionController) LogSessionRest(c echo.Context) error {
+	var rest models.SessionRest
+	if err := c.Bind(&rest); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid request body"))
+	}
+	if err := c.Validate(&rest); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err))
+	}
+	if rest.ID.IsZero() {
+		rest.ID = primitive.NewObjectID()
+	}
+	rest.LoggedAt = time.Now()
+
+	return sc.logToSession(c, http.StatusCreated,
+		bson.M{"rests._id": bson.M{"$ne": rest.ID}},
+		bson.M{"$push": bson.M{"rests": rest}},
+		nil, nil,
+	)
+}
+
+// FinishSession stops the session clock and writes each exercise's sets into the goal of
+// the same name on the session's date, creating goals that do not exist yet. Sets already
+// written are skipped, so finishing again after a failure or lost response is safe. An
+// exercise whose goal was replaced meanwhile by one that cannot hold its sets is listed as
+// unsaved rather than keeping the session from finishing.
+func (sc *SessionController) FinishSession(c echo.Context) error {
+	session, err := sc.findSession(c)
+	if err != nil {
+		return err
+	}
+	system := utils.UserUnits(c)
+	switch session.Status {
+	case models.SessionFinished:
+		return c.JSON(http.StatusOK, localizeSession(session, system))
+	case models.SessionAbandoned:
+		return c.JSON(http.StatusConflict, utils.ErrorResponse("Session was abandoned"))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	var dailyData models.DailyDataCollection
+	err = sc.dailyCollection.FindOne(ctx, bson.M{"userId": session.UserID, "date": session.Date}).Decode(&dailyData)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error retrieving goals"))
+	}
+
+	now := time.Now()
+	unsaved := []string{}
+	performed := make([]models.SessionExercise, 0, len(session.Exercises))
+	goals := make([]models.ExerciseGoal, 0, len(session.Exercises))
+	for _, entry := range session.Exercises {
+		if len(entry.Sets) == 0 {
+			continue
+		}
+		if sessionGoalConflict(findGoalByName(dailyData.Goals, entry.GoalName), entry.Type) != "" {
+			unsaved = append(unsaved, entry.GoalName)
+			continue
+		}
+		performed = append(performed, entry)
+		goals = append(goals, models.ExerciseGoal{
+			ID:         primitive.NewObjectID(),
+			UserID:     session.UserID,
+			ExerciseID: entry.ExerciseID,
+			GoalName:   entry.GoalName,
+			Type:       entry.Type,
+			GoalValue:  utils.Round(models.SetsProgress(entry.Type, entry.Sets), 2),
+			Intensity:  entry.Intensity,
+			IsActive:   true,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		})
+	}
+	if _, _, err := pushExerciseGoals(ctx, sc.dailyCollection, session.UserID, session.Date, goals); err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to create goals"))
+	}
+
+	dailyData = models.DailyDataCollection{}
+	err = sc.dailyCollection.FindOne(ctx, bson.M{"userId": session.UserID, "date": session.Date}).Decode(&dailyData)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error retrieving goals"))
+	}
+
+	goalIDs := make([]primitive.ObjectID, 0, len(performed))
+	for _, entry := range performed {
+		existing := findGoalByName(dailyData.Goals, entry.GoalName)
+		goalID, ok := existing["_id"].(primitive.ObjectID)
+		if !ok || sessionGoalConflict(existing, entry.Type) != "" {
+			unsaved = append(unsaved, entry.GoalName)
+			continue
+		}
+		goal, err := updateGoalSets(ctx, sc.dailyCollection, session.UserID, session.Date, goalID, func(goal *models.ExerciseGoal) error {
+			written := map[primitive.ObjectID]bool{}
+			for _, set := range goal.Sets {
+				written[set.ID] = true
+			}
+			for _, set := range entry.Sets {
+				if !written[set.ID] {
+					goal.Sets = append(goal.Sets, set)
+				}
+			}
+			if len(goal.Sets) > maxSetsPerGoal {
+				return errGoalFull
+			}
+			return nil
+		})
+		if err == errGoalFull {
+			unsaved = append(unsaved, entry.GoalName)
+			continue
+		} else if err != nil {
+			return err
+		}
+		if _, err := sc.records.track(ctx, session.UserID, session.Date, goal); err != nil {
+			log.Println("Error updating personal records:", err)
+		}
+		goalIDs = append(goalIDs, goalID)
+	}
+
+	restSeconds := 0
+	for _, rest := range session.Rests {
+		restSeconds += rest.DurationSeconds
+	}
+
+	var finished models.WorkoutSession
+	err = sc.collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": session.ID, "status": models.SessionActive},
+		bson.M{"$set": bson.M{
+			"status":          models.SessionFinished,
+			"finishedAt":      now,
+			"lastActivityAt":  now,
+			"durationSeconds": int(now.Sub(session.StartedAt).Seconds()),
+			"restSeconds":     restSeconds,
+			"goalIds":         goalIDs,
+			"unsaved":         unsaved,
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&finished)
+	if err == mongo.ErrNoDocuments {
+		// A concurrent request finished it first
+		return sc.GetSession(c)
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to finish session"))
+	}
+	return c.JSON(http.StatusOK, localizeSession(finished, system))
+}
+
+// errGoalFull stops a session's sets from being written to a goal that has no room for them
+var errGoalFull = errors.New("goal has too many sets")
+
+// sessionGoalConflict says why a day's goal cannot take the sets of a session exercise of
+// goalType, or returns "" when it can or when there is no such goal yet
+func sessionGoalConflict(goal map[string]interface{}, goalType string) string {
+	if len(goal) == 0 {
+		return ""
+	}
+	existingType, _ := goal["type"].(string)
+	if _, ok := goal["_id"].(primitive.ObjectID); !ok || (existingType != "reps" && existingType != "mins" && existingType != "kms") {
+		return "The day already has a goal of this name that is not an exercise goal; use another goalName"
+	}
+	if existingType != goalType {
+		return "The day's goal of this name is counted in " + existingType + "; use that type or another goalName"
+	}
+	return ""
+}
+
+// AbandonSession ends an active session without writing anything to goals
+func (sc *SessionController) AbandonSession(c echo.Context) error {
+	session, err := sc.findSession(c)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	result, err := sc.collection.UpdateOne(ctx,
+		bson.M{"_id": session.ID, "status": models.SessionActive},
+		bson.M{"$set": bson.M{"status": models.SessionAbandoned, "finishedAt": now, "lastActivityAt": now}},
+	)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to abandon session"))
+	}
+	if result.MatchedCount == 0 {
+		return c.JSON(http.StatusConflict, utils.ErrorResponse("Session is not in progress"))
+	}
+	return c.JSON(http.StatusOK, utils.SuccessResponse("Session abandoned"))
+}
+
+// findSession loads the session named by :id when the authenticated user owns it,
+// returning an *echo.HTTPError on failure
+func (sc *SessionController) findSession(c echo.Context) (models.WorkoutSession, error) {
+	var session models.WorkoutSession
+	userID := actorID(c)
+	if userID.IsZero() {
+		return session, echo.NewHTTPError(http.StatusUnauthorized, utils.ErrorResponse("Unauthorized"))
+	}
+	sessionID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return session, echo.NewHTTPError(http.StatusBadRequest, utils.ErrorResponse("Invalid session ID"))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = sc.collection.FindOne(ctx, bson.M{"_id": sessionID, "userId": userID}).Decode(&session)
+	if err == mongo.ErrNoDocuments {
+		return session, echo.NewHTTPError(http.StatusNotFound, utils.ErrorResponse("Session not found"))
+	} else if err != nil {
+		return session, echo.NewHTTPError(http.StatusInternalServerError, utils.ErrorResponse("Failed to fetch session"))
+	}
+	return session, nil
+}
+
+// logToSession applies update to the active session named by :id when it also matches
+// conditions, and responds with the session. When nothing matched, explain inspects the
+// session and returns an *echo.HTTPError saying why; a nil explain, or one that returns
+// nil, treats the request as a retry of one already applied.
+func (sc *SessionController) logToSession(c echo.Context, status int, conditions, update bson.M, opts *options.UpdateOptions, explain func(models.WorkoutSession) error) error {
+	session, err := sc.findSession(c)
+	if err != nil {
+		return err
+	}
+	if session.Status != models.SessionActive {
+		return c.JSON(http.StatusConflict, utils.ErrorResponse("Session is not in progress"))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": session.ID, "status": models.SessionActive}
+	for key, value := range conditions {
+		filter[key] = value
+	}
+	update["$set"] = bson.M{"lastActivityAt": time.Now()}
+	if opts == nil {
+		opts = options.Update()
+	}
+	result, err := sc.collection.UpdateOne(ctx, filter, update, opts)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to update session"))
+	}
+
+	session, err = sc.findSession(c)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		if session.Status != models.SessionActive {
+			return c.JSON(http.StatusConflict, utils.ErrorResponse("Session is not in progress"))
+		}
+		if explain != nil {
+			if err := explain(session); err != nil {
+				return err
+			}
+		}
+		status = http.StatusOK
+	}
+	return c.JSON(status, localizeSession(session, utils.UserUnits(c)))
+}
+
+// localizeSession converts the weights and distances of a session's sets into the user's
+// unit system
+func localizeSession(session models.WorkoutSession, system string) models.WorkoutSession {
+	exercises := make([]models.SessionExercise, len(session.Exercises))
+	for i, entry := range session.Exercises {
+		sets := make([]models.ExerciseSet, len(entry.Sets))
+		for j, set := range entry.Sets {
+			weight, _ := units.ToDisplay(set.Weight, units.Kilogram, system)
+			distance, _ := units.ToDisplay(set.Distance, units.Kilometer, system)
+			set.Weight, set.Distance = utils.Round(weight, 2), utils.Round(distance, 2)
+			sets[j] = set
+		}
+		entry.Sets = sets
+		exercises[i] = entry
+	}
+	session.Exercises = exercises
+	return session
+}
@@ -0,0 +1,273 @@
+package controllers
+
+import (
+	"context"
+	"fitness-backend/models"
+	"fitness-backend/units"
+	"fitness-backend/utils"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type TemplateController struct {
+	collection         *mongo.Collection
+	dailyCollection    *mongo.Collection
+	exerciseCollection *mongo.Collection
+}
+
+// NewTemplateController initializes a new instance of TemplateController
+func NewTemplateController(db *mongo.Database) *TemplateController {
+	return &TemplateController{
+		collection:         db.Collection("workout_templates"),
+		dailyCollection:    db.Collection("daily_data"),
+		exerciseCollection: db.Collection("exercise_guides"),
+	}
+}
+
+// CreateTemplate saves a new workout template for the authenticated user
+func (tc *TemplateController) CreateTemplate(c echo.Context) error {
+	userID := actorID(c)
+	if userID.IsZero() {
+		return c.JSON(http.StatusUnauthorized, utils.ErrorResponse("Unauthorized"))
+	}
+
+	template, err := tc.bindTemplate(c, userID)
+	if err != nil {
+		return err
+	}
+	template.ID = primitive.NewObjectID()
+	template.UserID = userID
+	template.CreatedAt = time.Now()
+	template.UpdatedAt = template.CreatedAt
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := tc.collection.InsertOne(ctx, template); mongo.IsDuplicateKeyError(err) {
+		return c.JSON(http.StatusConflict, utils.ErrorResponse("You already have a template with this name"))
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to create template"))
+	}
+
+	return c.JSON(http.StatusCreated, localizeTemplate(template, utils.UserUnits(c)))
+}
+
+// GetTemplates lists the authenticated user's workout templates by name
+func (tc *TemplateController) GetTemplates(c echo.Context) error {
+	userID := actorID(c)
+	if userID.IsZero() {
+		return c.JSON(http.StatusUnauthorized, utils.ErrorResponse("Unauthorized"))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := tc.collection.Find(ctx, bson.M{"userId": userID}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to fetch templates"))
+	}
+	templates := make([]models.WorkoutTemplate, 0)
+	if err := cursor.All(ctx, &templates); err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Error decoding templates"))
+	}
+
+	system := utils.UserUnits(c)
+	for i := range templates {
+		templates[i] = localizeTemplate(templates[i], system)
+	}
+	return c.JSON(http.StatusOK, templates)
+}
+
+// GetTemplate returns one of the authenticated user's workout templates
+func (tc *TemplateController) GetTemplate(c echo.Context) error {
+	template, err := tc.findTemplate(c)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, localizeTemplate(template, utils.UserUnits(c)))
+}
+
+// UpdateTemplate replaces the name, description and exercises of a workout template
+func (tc *TemplateController) UpdateTemplate(c echo.Context) error {
+	current, err := tc.findTemplate(c)
+	if err != nil {
+		return err
+	}
+
+	template, err := tc.bindTemplate(c, current.UserID)
+	if err != nil {
+		return err
+	}
+	template.ID = current.ID
+	template.UserID = current.UserID
+	template.CreatedAt = current.CreatedAt
+	template.UpdatedAt = time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := tc.collection.ReplaceOne(ctx, bson.M{"_id": current.ID, "userId": current.UserID}, template); mongo.IsDuplicateKeyError(err) {
+		return c.JSON(http.StatusConflict, utils.ErrorResponse("You already have a template with this name"))
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to update template"))
+	}
+
+	return c.JSON(http.StatusOK, localizeTemplate(template, utils.UserUnits(c)))
+}
+
+// DeleteTemplate removes one of the authenticated user's workout templates. Goals already
+// created from it are kept.
+func (tc *TemplateController) DeleteTemplate(c echo.Context) error {
+	template, err := tc.findTemplate(c)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := tc.collection.DeleteOne(ctx, bson.M{"_id": template.ID, "userId": template.UserID}); err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to delete template"))
+	}
+	return c.JSON(http.StatusOK, utils.SuccessResponse("Template deleted successfully"))
+}
+
+// ApplyTemplate adds a template's exercises as goals on a date. Exercises whose goal name
+// the day already has are skipped, so applying a template twice is harmless.
+func (tc *TemplateController) ApplyTemplate(c echo.Context) error {
+	template, err := tc.findTemplate(c)
+	if err != nil {
+		return err
+	}
+
+	date, err := utils.ResolveDate(c, c.Param("date"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("Invalid date format"))
+	}
+
+	now := time.Now()
+	goals := make([]models.ExerciseGoal, 0, len(template.Exercises))
+	for _, exercise := range template.Exercises {
+		goals = append(goals, exercise.Goal(template.UserID, now))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	added, skipped, err := pushExerciseGoals(ctx, tc.dailyCollection, template.UserID, date, goals)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("Failed to apply template"))
+	}
+
+	system := utils.UserUnits(c)
+	localized := make([]interface{}, 0, len(added))
+	for _, goal := range added {
+		localized = append(localized, localizeGoalResponse(goal, system))
+	}
+
+	status := http.StatusCreated
+	if len(added) == 0 {
+		status = http.StatusOK
+	}
+	return c.JSON(status, echo.Map{
+		"templateId": template.ID,
+		"date":       date.Format(utils.DateLayout),
+		"added":      localized,
+		"skipped":    skipped,
+	})
+}
+
+// findTemplate loads the template named by :id when the authenticated user owns it,
+// returning an *echo.HTTPError on failure
+func (tc *TemplateController) findTemplate(c echo.Context) (models.WorkoutTemplate, error) {
+	userID := actorID(c)
+	if userID.IsZero() {
+		return models.WorkoutTemplate{}, echo.NewHTTPError(http.StatusUnauthorized, utils.ErrorResponse("Unauthorized"))
+	}
+	templateID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return models.WorkoutTemplate{}, echo.NewHTTPError(http.StatusBadRequest, utils.ErrorResponse("Invalid template ID"))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var template models.WorkoutTemplate
+	err = tc.collection.FindOne(ctx, bson.M{"_id": templateID, "userId": userID}).Decode(&template)
+	if err == mongo.ErrNoDocuments {
+		return template, echo.NewHTTPError(http.StatusNotFound, utils.ErrorResponse("Template not found"))
+	} else if err != nil {
+		return template, echo.NewHTTPError(http.StatusInternalServerError, utils.ErrorResponse("Failed to fetch template"))
+	}
+	return template, nil
+}
+
+// bindTemplate reads and validates a template from the request body, returning an
+// *echo.HTTPError on failure. Targets are converted to canonical units and each exercise is
+// linked to the user's private exercise or the catalog; a missing goal name defaults to the
+// linked exercise's name.
+func (tc *TemplateController) bindTemplate(c echo.Context, userID primitive.ObjectID) (models.WorkoutTemplate, error) {
+	var template models.WorkoutTemplate
+	if err := c.Bind(&template); err != nil {
+		return template, echo.NewHTTPError(http.StatusBadRequest, utils.ErrorResponse("Invalid request body"))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	names := map[string]bool{}
+	for i := range template.Exercises {
+		te := &template.Exercises[i]
+		te.GoalValue, te.Type = units.ToCanonical(te.GoalValue, te.Type)
+
+		if !te.ExerciseID.IsZero() {
+			var exercise models.Exercise
+			filter := bson.M{"$and": bson.A{bson.M{"_id": te.ExerciseID, "deletedAt": nil}, exerciseVisibility(userID)}}
+			if err := tc.exerciseCollection.FindOne(ctx, filter).Decode(&exercise); err == mongo.ErrNoDocuments {
+				return template, echo.NewHTTPError(http.StatusBadRequest, utils.ErrorResponse("Exercise not found: "+te.ExerciseID.Hex()))
+			} else if err != nil {
+				return template, echo.NewHTTPError(http.StatusInternalServerError, utils.ErrorResponse("Failed to fetch exercise"))
+			}
+			if te.GoalName == "" {
+				te.GoalName = exercise.Name
+			}
+		} else if te.GoalName != "" {
+			exercise, err := findExerciseForUser(ctx, tc.exerciseCollection, userID, te.GoalName)
+			if err != nil && err != mongo.ErrNoDocuments {
+				return template, echo.NewHTTPError(http.StatusInternalServerError, utils.ErrorResponse("Failed to fetch exercise"))
+			}
+			te.ExerciseID = exercise.ID
+		}
+
+		// Goal names identify goals within a day, so a template cannot repeat one
+		if te.GoalName != "" && names[te.GoalName] {
+			return template, echo.NewHTTPError(http.StatusBadRequest, utils.ErrorResponse("Each exercise in a template needs a different goalName: "+te.GoalName))
+		}
+		names[te.GoalName] = true
+	}
+
+	if err := c.Validate(&template); err != nil {
+		return template, echo.NewHTTPError(http.StatusBadRequest, utils.ValidationErrorResponse(err))
+	}
+	return template, nil
+}
+
+// localizeTemplate converts a template's distance targets into the user's unit system
+func localizeTemplate(template models.WorkoutTemplate, system string) models.WorkoutTemplate {
+	exercises := make([]models.TemplateExercise, len(template.Exercises))
+	for i, te := range template.Exercises {
+		if units.IsConvertible(te.Type) {
+			value, unit := units.ToDisplay(te.GoalValue, te.Type, system)
+			te.GoalValue, te.Type = utils.Round(value, 2), unit
+		}
+		exercises[i] = te
+	}
+	template.Exercises = exercises
+	return template
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ExerciseTextIndex is the name of the full-text index over the exercise catalog
+const ExerciseTextIndex = "exercise_text"
+
+// indexes lists the indexes each collection needs. Creating an index that already
+// exists with the same definition is a no-op, so this is safe to run on every start.
+var indexes = map[string][]mongo.IndexModel{
+	"exercise_guides": {
+		{
+			Keys: bson.D{
+				{Key: "name", Value: "text"},
+				{Key: "mainMuscles", Value: "text"},
+				{Key: "benefits", Value: "text"},
+				{Key: "steps", Value: "text"},
+			},
+			// Matches in the name matter most, then the muscles worked
+			Options: options.Index().SetName(ExerciseTextIndex).SetWeights(bson.D{
+				{Key: "name", Value: 10},
+				{Key: "mainMuscles", Value: 5},
+				{Key: "benefits", Value: 2},
+				{Key: "steps", Value: 1},
+			}),
+		},
+		{Keys: bson.D{{Key: "category", Value: 1}, {Key: "_id", Value: 1}}},
+		// Goals link to exercises by owner and name
+		{Keys: bson.D{{Key: "ownerId", Value: 1}, {Key: "name", Value: 1}}},
+	},
+	"exercise_revisions": {
+		{Keys: bson.D{{Key: "exerciseId", Value: 1}, {Key: "revision", Value: 1}}, Options: options.Index().SetUnique(true)},
+	},
+	"daily_data": {
+		// One document per user and day, so goals upserted concurrently land together
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "date", Value: 1}}, Options: options.Index().SetUnique(true)},
+	},
+	"workout_templates": {
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "name", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "exercises.exerciseId", Value: 1}}},
+	},
+	"training_programs": {
+		{Keys: bson.D{{Key: "workouts.exercises.exerciseId", Value: 1}}},
+	},
+	"program_enrollments": {
+		// One running enrollment per user and program
+		{
+			Keys: bson.D{{Key: "userId", Value: 1}, {Key: "programId", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{
+				"status": bson.M{"$in": bson.A{"active", "paused"}},
+			}),
+		},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "status", Value: 1}}},
+		{Keys: bson.D{{Key: "programId", Value: 1}, {Key: "status", Value: 1}}},
+	},
+	"personal_records": {
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "exerciseId", Value: 1}, {Key: "kind", Value: 1}}, Options: options.Index().SetUnique(true)},
+	},
+	"workout_sessions": {
+		// One session in progress per user
+		{Keys: bson.D{{Key: "userId", Value: 1}}, Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"status": "active"})},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "startedAt", Value: -1}}},
+	},
+	"recurring_goals": {
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "startDate", Value: 1}}},
+	},
+	"refresh_tokens": {
+		{Keys: bson.D{{Key: "tokenHash", Value: 1}}, Options: options.Index().SetUnique(true)},
+	},
+	"action_tokens": {
+		{Keys: bson.D{{Key: "tokenHash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "email", Value: 1}, {Key: "purpose", Value: 1}, {Key: "createdAt", Value: -1}}},
+	},
+}
+
+// EnsureIndexes creates the indexes the application relies on
+func EnsureIndexes(ctx context.Context, db *mongo.Database) error {
+	for collection, collectionIndexes := range indexes {
+		if _, err := db.Collection(collection).Indexes().CreateMany(ctx, collectionIndexes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
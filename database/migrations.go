@@ -0,0 +1,231 @@
+package database
+
+import (
+	"context"
+	"fitness-backend/catalog"
+	"fitness-backend/models"
+	"fitness-backend/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// migrations bring documents written by earlier versions up to date. Each one only touches
+// documents that still need it, so they are safe to run on every start.
+var migrations = []func(ctx context.Context, db *mongo.Database) error{
+	dropSetlessSingleEffortRecords,
+	backfillCatalogEquipment,
+	parseStringDurations,
+	cancelDuplicateEnrollments,
+	mergeDuplicateDailyData,
+}
+
+// RunMigrations applies every migration in order. It runs before EnsureIndexes so that data
+// a new unique index would reject is cleaned up first.
+func RunMigrations(ctx context.Context, db *mongo.Database) error {
+	for _, migrate := range migrations {
+		if err := migrate(ctx, db); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dropSetlessSingleEffortRecords removes the history entries of single-set records that were
+// taken from a whole day's progress on goals without sets. The best remaining entry becomes
+// the record again; records left without entries are deleted.
+func dropSetlessSingleEffortRecords(ctx context.Context, db *mongo.Database) error {
+	records := db.Collection("personal_records")
+	kinds := bson.M{"$in": bson.A{models.RecordMaxReps, models.RecordLongestDistance}}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"history": bson.M{"$filter": bson.M{
+			"input": "$history",
+			"as":    "entry",
+			"cond":  bson.M{"$ne": bson.A{bson.M{"$type": "$$entry.setId"}, "missing"}},
+		}}}}},
+		{{Key: "$set", Value: bson.M{"best": bson.M{"$arrayElemAt": bson.A{"$history", -1}}}}},
+		{{Key: "$set", Value: bson.M{
+			"value":      "$best.value",
+			"date":       "$best.date",
+			"goalId":     "$best.goalId",
+			"setId":      "$best.setId",
+			"achievedAt": "$best.achievedAt",
+		}}},
+		{{Key: "$unset", Value: "best"}},
+	}
+	_, err := records.UpdateMany(ctx,
+		bson.M{"kind": kinds, "history": bson.M{"$elemMatch": bson.M{"setId": bson.M{"$exists": false}}}},
+		pipeline,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = records.DeleteMany(ctx, bson.M{"kind": kinds, "history": bson.M{"$size": 0}})
+	return err
+}
+
+// backfillCatalogEquipment gives global exercises with unknown equipment, because they were
+// saved before equipment existed or imported without it, the equipment of the bundled
+// catalog entry with the same name, or an empty list for bodyweight entries. Other exercises
+// keep no list, and so stay out of location filtering, until an admin sets one.
+func backfillCatalogEquipment(ctx context.Context, db *mongo.Database) error {
+	rows, err := catalog.Default()
+	if err != nil {
+		return err
+	}
+	exercises := db.Collection("exercise_guides")
+	for _, row := range rows {
+		if row.Err != nil {
+			continue
+		}
+		_, err := exercises.UpdateMany(ctx,
+			bson.M{"name": row.Exercise.Name, "ownerId": nil, "equipment": bson.M{"$not": bson.M{"$type": "array"}}},
+			bson.M{"$set": bson.M{"equipment": models.NormalizeEquipment(row.Exercise.Equipment)}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseStringDurations stores estimated times still saved as text, such as "10-15 minutes",
+// as minute ranges. Text that cannot be read is left for an admin to fix.
+func parseStringDurations(ctx context.Context, db *mongo.Database) error {
+	exercises := db.Collection("exercise_guides")
+	cursor, err := exercises.Find(ctx, bson.M{"estimatedTime": bson.M{"$type": "string"}})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID            interface{} `bson:"_id"`
+			EstimatedTime string      `bson:"estimatedTime"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+		duration, err := models.ParseDurationRange(doc.EstimatedTime)
+		if err != nil {
+			continue
+		}
+		_, err = exercises.UpdateOne(ctx,
+			bson.M{"_id": doc.ID, "estimatedTime": doc.EstimatedTime},
+			bson.M{"$set": bson.M{"estimatedTime": duration}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// cancelDuplicateEnrollments keeps the oldest of several running enrollments a user has in
+// the same program, which concurrent enrollment requests could create, and cancels the rest
+// from their start date. Goals they already created stay.
+func cancelDuplicateEnrollments(ctx context.Context, db *mongo.Database) error {
+	enrollments := db.Collection("program_enrollments")
+	running := bson.M{"$in": bson.A{models.EnrollmentActive, models.EnrollmentPaused}}
+
+	cursor, err := enrollments.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"status": running}}},
+		{{Key: "$sort", Value: bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{"userId": "$userId", "programId": "$programId"},
+			"ids": bson.M{"$push": "$_id"},
+		}}},
+		{{Key: "$match", Value: bson.M{"ids.1": bson.M{"$exists": true}}}},
+	})
+	if err != nil {
+		return err
+	}
+	var groups []struct {
+		IDs []primitive.ObjectID `bson:"ids"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return err
+	}
+
+	for _, group := range groups {
+		_, err := enrollments.UpdateMany(ctx,
+			bson.M{"_id": bson.M{"$in": group.IDs[1:]}, "status": running},
+			mongo.Pipeline{{{Key: "$set", Value: bson.M{
+				"status":    models.EnrollmentCancelled,
+				"endDate":   "$startDate",
+				"updatedAt": "$$NOW",
+			}}}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeDuplicateDailyData folds the daily_data documents that concurrent first writes to a
+// day could create into the oldest one. Goals keep their order; when two documents have a
+// goal of the same name, the one with more progress is kept.
+func mergeDuplicateDailyData(ctx context.Context, db *mongo.Database) error {
+	daily := db.Collection("daily_data")
+	cursor, err := daily.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":  bson.M{"userId": "$userId", "date": "$date"},
+			"docs": bson.M{"$push": bson.M{"_id": "$_id", "goals": "$goals"}},
+		}}},
+		{{Key: "$match", Value: bson.M{"docs.1": bson.M{"$exists": true}}}},
+	}, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return err
+	}
+	var groups []struct {
+		Docs []struct {
+			ID    primitive.ObjectID `bson:"_id"`
+			Goals []bson.M           `bson:"goals"`
+		} `bson:"docs"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return err
+	}
+
+	for _, group := range groups {
+		goals := []bson.M{}
+		byName := map[string]int{}
+		duplicates := bson.A{}
+		for i, doc := range group.Docs {
+			if i > 0 {
+				duplicates = append(duplicates, doc.ID)
+			}
+			for _, goal := range doc.Goals {
+				name, ok := goal["goalName"].(string)
+				if !ok {
+					goals = append(goals, goal)
+					continue
+				}
+				if index, seen := byName[name]; seen {
+					kept, _ := utils.ConvertToFloat(goals[index]["progressValue"])
+					other, _ := utils.ConvertToFloat(goal["progressValue"])
+					if other > kept {
+						goals[index] = goal
+					}
+					continue
+				}
+				byName[name] = len(goals)
+				goals = append(goals, goal)
+			}
+		}
+
+		if _, err := daily.UpdateOne(ctx, bson.M{"_id": group.Docs[0].ID}, bson.M{"$set": bson.M{"goals": goals}}); err != nil {
+			return err
+		}
+		if _, err := daily.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": duplicates}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fitness-backend/utils"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrNotConfigured is returned by Unconfigured when no mailer was set up
+var ErrNotConfigured = errors.New("no mailer is configured; set MAILER")
+
+// Message is a plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+	Secrets []string // Values in Body, such as single-use tokens, that must never be logged
+}
+
+// Mailer delivers transactional emails such as password reset links
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Unconfigured fails every send, so a missing mailer is reported instead of mail silently vanishing
+type Unconfigured struct{}
+
+func (Unconfigured) Send(ctx context.Context, msg Message) error {
+	return ErrNotConfigured
+}
+
+// LogMailer writes messages to the application log instead of sending them, with their
+// secrets redacted. It is only useful for local development.
+type LogMailer struct{}
+
+func (LogMailer) Send(ctx context.Context, msg Message) error {
+	body := msg.Body
+	for _, secret := range msg.Secrets {
+		if secret != "" {
+			body = strings.ReplaceAll(body, secret, "[redacted]")
+		}
+	}
+	log.Printf("mail to=%s subject=%q\n%s", msg.To, msg.Subject, body)
+	return nil
+}
+
+// FileMailer writes each message to its own file in Dir, which is handy for local development and tests
+type FileMailer struct {
+	Dir string
+}
+
+func (m FileMailer) Send(ctx context.Context, msg Message) error {
+	if err := os.MkdirAll(m.Dir, 0o700); err != nil {
+		return err
+	}
+	recipient := strings.NewReplacer("@", "_at_", "/", "_").Replace(msg.To)
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), recipient)
+	content := fmt.Sprintf("To: %s\nSubject: %s\n\n%s\n", msg.To, msg.Subject, msg.Body)
+	return os.WriteFile(filepath.Join(m.Dir, name), []byte(content), 0o600)
+}
+
+// SMTPMailer sends messages through an SMTP server, upgrading to TLS when the server offers it
+type SMTPMailer struct {
+	Addr     string // host:port
+	Username string
+	Password string
+	From     string
+}
+
+func (m SMTPMailer) Send(ctx context.Context, msg Message) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, _ := net.SplitHostPort(m.Addr)
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+	content := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n",
+		m.From, msg.To, msg.Subject, strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return smtp.SendMail(m.Addr, auth, m.From, []string{msg.To}, []byte(content))
+}
+
+// FromEnv builds the mailer named by the MAILER environment variable. "smtp" sends real
+// email using SMTP_HOST, SMTP_PORT (default 587), SMTP_USERNAME, SMTP_PASSWORD and
+// MAIL_FROM. "log" and "file" (into MAIL_DIR) are for local development and must be chosen
+// explicitly. An unset or unknown MAILER is an error, so a deployment cannot start without
+// a way to deliver reset and verification emails.
+func FromEnv() (Mailer, error) {
+	switch name := utils.GetEnvVariable("MAILER"); name {
+	case "smtp":
+		host := utils.GetEnvVariable("SMTP_HOST")
+		from := utils.GetEnvVariable("MAIL_FROM")
+		if host == "" || from == "" {
+			return nil, errors.New("MAILER=smtp needs SMTP_HOST and MAIL_FROM")
+		}
+		port := utils.GetEnvVariable("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		return SMTPMailer{
+			Addr:     net.JoinHostPort(host, port),
+			Username: utils.GetEnvVariable("SMTP_USERNAME"),
+			Password: utils.GetEnvVariable("SMTP_PASSWORD"),
+			From:     from,
+		}, nil
+	case "file":
+		dir := utils.GetEnvVariable("MAIL_DIR")
+		if dir == "" {
+			dir = "mail"
+		}
+		return FileMailer{Dir: dir}, nil
+	case "log":
+		return LogMailer{}, nil
+	case "":
+		return nil, errors.New("MAILER is not set; use smtp, or log or file for local development")
+	default:
+		return nil, fmt.Errorf("unknown MAILER %q; expected smtp, log or file", name)
+	}
+}
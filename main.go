@@ -3,11 +3,14 @@ package main
 import (
 	"context"
 	"fitness-backend/controllers"
+	"fitness-backend/database"
+	"fitness-backend/mailer"
 	"fitness-backend/routes"
 	"fitness-backend/utils"
 	"log"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -25,10 +28,42 @@ func main() {
 	defer client.Disconnect(context.Background())
 
 	db := client.Database("fitness")
+
+	migrateCtx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	if err := database.RunMigrations(migrateCtx, db); err != nil {
+		log.Fatal("Failed to migrate data: ", err)
+	}
+	cancel()
+
+	indexCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	if err := database.EnsureIndexes(indexCtx, db); err != nil {
+		log.Fatal("Failed to create indexes: ", err)
+	}
+	cancel()
+
+	// Seed the bundled exercise catalog on a fresh deployment unless disabled
+	if os.Getenv("SEED_DEFAULT_CATALOG") != "false" {
+		seedCtx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		report, seeded, err := controllers.NewExerciseGuideController(db).SeedDefaultCatalog(seedCtx)
+		cancel()
+		if err != nil {
+			log.Fatal("Failed to seed exercise catalog: ", err)
+		}
+		if seeded {
+			log.Printf("Seeded exercise catalog with %d exercises", report.Created)
+		}
+	}
+
+	mail, err := mailer.FromEnv()
+	if err != nil {
+		log.Fatal("Failed to configure mailer: ", err)
+	}
 	authController := controllers.NewAuthController(db)
+	authController.Mailer = mail
 
 	// Setup Echo
 	e := echo.New()
+	e.Validator = utils.NewValidator()
 
 	// Get frontend origins from environment variable
 	frontendOrigins := os.Getenv("FRONTEND_ORIGINS")
@@ -41,17 +76,37 @@ func main() {
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOrigins: origins, // Use the frontend origins from the environment variable
 		AllowMethods: []string{echo.GET, echo.POST, echo.PUT, echo.DELETE, echo.PATCH},
+		// Pagination metadata is sent in headers, which browsers hide unless exposed
+		ExposeHeaders: []string{"X-Total-Count", "X-Next-Cursor"},
 	}))
 
 	// Auth routes
 	auth := e.Group("/auth")
 	auth.POST("/signup", authController.SignUp)
 	auth.POST("/login", authController.Login)
+	auth.POST("/refresh", authController.Refresh)
+	auth.POST("/logout", authController.Logout)
+	auth.POST("/password/forgot", authController.ForgotPassword)
+	auth.POST("/password/reset", authController.ResetPassword)
+	auth.GET("/verify", authController.VerifyEmail)
+	auth.POST("/verify/resend", authController.ResendVerification)
 
 	//Routes for exercises
 	routes.RegisterExerciseRoutes(e, db)
 	//Routes for calories
 	routes.RegisterFoodRoutes(e, db)
+	//Routes for user profiles
+	routes.RegisterUserRoutes(e, db)
+	//Routes for workout templates
+	routes.RegisterTemplateRoutes(e, db)
+	//Routes for training programs
+	routes.RegisterProgramRoutes(e, db)
+	//Routes for live workout sessions
+	routes.RegisterSessionRoutes(e, db)
+	//Routes for recurring goals
+	routes.RegisterRecurringGoalRoutes(e, db)
+	//Routes for admins
+	routes.RegisterAdminRoutes(e, db)
 
 	port := utils.GetEnvVariable("PORT")
 	if port == "" {
@@ -1,33 +1,106 @@
 package middleware
 
 import (
+	"context"
+	"fitness-backend/models"
 	"fitness-backend/utils"
 	"net/http"
 	"strings"
 
 	"github.com/golang-jwt/jwt"
 	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func AuthMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
-	return func(c echo.Context) error {
-		authHeader := c.Request().Header.Get("Authorization")
-		if authHeader == "" {
-			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Authorization header required"})
-		}
+// AuthMiddleware validates the access token, rejects tokens whose session has been revoked
+// and applies the unverified email policy
+func AuthMiddleware(db *mongo.Database) echo.MiddlewareFunc {
+	sessions := db.Collection("auth_sessions")
+	users := db.Collection("users")
+	verificationPolicy := unverifiedAccessPolicy()
 
-		bearerToken := strings.Split(authHeader, " ")
-		if len(bearerToken) != 2 {
-			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid token format"})
-		}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			authHeader := c.Request().Header.Get("Authorization")
+			if authHeader == "" {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Authorization header required"})
+			}
+
+			bearerToken := strings.Split(authHeader, " ")
+			if len(bearerToken) != 2 {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid token format"})
+			}
+
+			token, err := utils.VerifyToken(bearerToken[1])
+			if err != nil || !token.Valid {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid token"})
+			}
+
+			claims := token.Claims.(jwt.MapClaims)
+			sessionIDString, _ := claims["sid"].(string)
+			sessionID, err := primitive.ObjectIDFromHex(sessionIDString)
+			if err != nil {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid token"})
+			}
 
-		token, err := utils.VerifyToken(bearerToken[1])
-		if err != nil || !token.Valid {
-			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid token"})
+			count, err := sessions.CountDocuments(c.Request().Context(), bson.M{
+				"_id":       sessionID,
+				"revokedAt": bson.M{"$exists": false},
+			})
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Error checking session"})
+			}
+			if count == 0 {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Session revoked"})
+			}
+
+			c.Set("user_id", claims["user_id"])
+			c.Set("session_id", sessionIDString)
+			role, _ := claims["role"].(string)
+			if role == "" {
+				role = models.RoleUser
+			}
+			c.Set("role", role)
+			tz, _ := claims["tz"].(string)
+			units, _ := claims["units"].(string)
+			if method := c.Request().Method; method != http.MethodGet && method != http.MethodHead {
+				// Writes read input in the user's units and time zone, so they use the stored
+				// preferences rather than claims that may predate a profile change
+				tz, units, err = storedPreferences(c.Request().Context(), users, claims["user_id"], tz, units)
+				if err != nil {
+					return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Error fetching user preferences"})
+				}
+			}
+			c.Set("timezone", tz)
+			c.Set("units", units)
+
+			verified, _ := claims["verified"].(bool)
+			c.Set("verified", verified)
+			if !verificationAllows(c, verificationPolicy, verified) {
+				return c.JSON(http.StatusForbidden, map[string]string{"error": "Email address not verified", "policy": verificationPolicy})
+			}
+			return next(c)
 		}
+	}
+}
 
-		claims := token.Claims.(jwt.MapClaims)
-		c.Set("user_id", claims["user_id"])
-		return next(c)
+// storedPreferences returns the time zone and unit system saved on the user, falling back
+// to the given claim values when the user cannot be found
+func storedPreferences(ctx context.Context, users *mongo.Collection, userIDClaim interface{}, tz, units string) (string, string, error) {
+	userIDString, _ := userIDClaim.(string)
+	userID, err := primitive.ObjectIDFromHex(userIDString)
+	if err != nil {
+		return tz, units, nil
+	}
+	var user models.User
+	err = users.FindOne(ctx, bson.M{"_id": userID}, options.FindOne().SetProjection(bson.M{"units": 1, "timezone": 1})).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return tz, units, nil
+	} else if err != nil {
+		return tz, units, err
 	}
+	return user.Timezone, user.Units, nil
 }
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// RequireRole only lets requests through when the authenticated user has one of the given roles.
+// The roles it guards are privileged, so unverified accounts are refused whatever the
+// UNVERIFIED_ACCESS policy. It must run after AuthMiddleware, which puts the role and
+// verified claims on the context.
+func RequireRole(roles ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			role, _ := c.Get("role").(string)
+			if verified, _ := c.Get("verified").(bool); !verified {
+				return c.JSON(http.StatusForbidden, echo.Map{
+					"error":         "Email address not verified",
+					"requiredRoles": roles,
+				})
+			}
+			for _, allowed := range roles {
+				if role == allowed {
+					return next(c)
+				}
+			}
+			return c.JSON(http.StatusForbidden, echo.Map{
+				"error":         "Insufficient permissions",
+				"role":          role,
+				"requiredRoles": roles,
+			})
+		}
+	}
+}
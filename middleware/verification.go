@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"fitness-backend/utils"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Policies for what accounts with an unverified email may access, set through UNVERIFIED_ACCESS.
+// Accounts created before email verification existed are unverified, so stricter
+// policies should only be enabled once existing users have been backfilled.
+const (
+	UnverifiedAccessFull     = "full"      // Unverified accounts can use every endpoint (default)
+	UnverifiedAccessReadOnly = "read-only" // Unverified accounts can only read data
+	UnverifiedAccessNone     = "none"      // Unverified accounts are rejected entirely
+)
+
+// unverifiedAccessPolicy returns the configured policy, defaulting to full access
+func unverifiedAccessPolicy() string {
+	switch policy := utils.GetEnvVariable("UNVERIFIED_ACCESS"); policy {
+	case UnverifiedAccessReadOnly, UnverifiedAccessNone:
+		return policy
+	default:
+		return UnverifiedAccessFull
+	}
+}
+
+// verificationAllows reports whether the policy lets the request through
+func verificationAllows(c echo.Context, policy string, verified bool) bool {
+	if verified || policy == UnverifiedAccessFull {
+		return true
+	}
+
+	method := c.Request().Method
+	return policy == UnverifiedAccessReadOnly && (method == http.MethodGet || method == http.MethodHead)
+}
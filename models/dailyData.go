@@ -20,47 +20,81 @@ type DailyDataCollection struct {
 
 // ExerciseGoal represents a goal for an exercise.
 type ExerciseGoal struct {
-	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`            // Unique identifier
-	UserID        primitive.ObjectID `bson:"userId" json:"userId"`               // Reference to the user
-	ExerciseID    primitive.ObjectID `bson:"exerciseId" json:"exerciseId"`       // Reference to the exercise
-	GoalName      string             `bson:"goalName" json:"goalName"`           // Name or description of the goal
-	Type          string             `bson:"type" json:"type"`                   // "reps", "mins", or "kms"
-	GoalValue     float64            `bson:"goalValue" json:"goalValue"`         // Target value for the goal
-	ProgressValue float64            `bson:"progressValue" json:"progressValue"` // Current progress towards the goal
-	Comments      string             `bson:"comments" json:"comments"`           // Additional comments
-	IsActive      bool               `bson:"isActive" json:"isActive"`           // Indicates if the goal is active
-	CreatedAt     time.Time          `bson:"createdAt" json:"createdAt"`         // Creation timestamp
-	UpdatedAt     time.Time          `bson:"updatedAt" json:"updatedAt"`         // Last update timestamp
+	ID             primitive.ObjectID  `bson:"_id,omitempty" json:"id"`                                                                           // Unique identifier
+	UserID         primitive.ObjectID  `bson:"userId" json:"userId"`                                                                              // Reference to the user
+	ExerciseID     primitive.ObjectID  `bson:"exerciseId" json:"exerciseId"`                                                                      // Reference to the exercise
+	GoalName       string              `bson:"goalName" json:"goalName" validate:"required"`                                                      // Name or description of the goal
+	Type           string              `bson:"type" json:"type" validate:"required,oneof=reps mins kms"`                                          // "reps", "mins", or "kms"
+	GoalValue      float64             `bson:"goalValue" json:"goalValue" validate:"gte=0,lte=100000"`                                            // Target value for the goal
+	ProgressValue  float64             `bson:"progressValue" json:"progressValue" validate:"gte=0,lte=100000"`                                    // Current progress towards the goal
+	Intensity      string              `bson:"intensity,omitempty" json:"intensity,omitempty" validate:"omitempty,oneof=light moderate vigorous"` // Selects the exercise's MET for calorie estimates
+	Comments       string              `bson:"comments" json:"comments"`                                                                          // Additional comments
+	EnrollmentID   *primitive.ObjectID `bson:"enrollmentId,omitempty" json:"enrollmentId,omitempty"`                                              // Program enrollment that scheduled the goal, if any
+	Sets           []ExerciseSet       `bson:"sets,omitempty" json:"sets,omitempty" validate:"max=100,dive"`                                      // Performed sets; when present ProgressValue is their total plus ManualProgress
+	ManualProgress float64             `bson:"manualProgress,omitempty" json:"manualProgress,omitempty"`                                          // Progress recorded before the first set
+	IsActive       bool                `bson:"isActive" json:"isActive"`                                                                          // Indicates if the goal is active
+	CreatedAt      time.Time           `bson:"createdAt" json:"createdAt"`                                                                        // Creation timestamp
+	UpdatedAt      time.Time           `bson:"updatedAt" json:"updatedAt"`                                                                        // Last update timestamp
+}
+
+// ExerciseSet is one performed set of an exercise goal, such as 8 reps at 60kg. Weight is
+// stored in kg and distance in kms.
+type ExerciseSet struct {
+	ID              primitive.ObjectID `bson:"_id" json:"id"`
+	Reps            int                `bson:"reps,omitempty" json:"reps,omitempty" validate:"gte=0,lte=1000"`
+	Weight          float64            `bson:"weight,omitempty" json:"weight,omitempty" validate:"gte=0,lte=1000"`
+	DurationSeconds int                `bson:"durationSeconds,omitempty" json:"durationSeconds,omitempty" validate:"gte=0,lte=86400"`
+	Distance        float64            `bson:"distance,omitempty" json:"distance,omitempty" validate:"gte=0,lte=1000"`
+	RPE             float64            `bson:"rpe,omitempty" json:"rpe,omitempty" validate:"omitempty,gte=1,lte=10"` // Rate of perceived exertion
+	RestSeconds     int                `bson:"restSeconds,omitempty" json:"restSeconds,omitempty" validate:"gte=0,lte=3600"`
+	LoggedAt        time.Time          `bson:"loggedAt" json:"loggedAt"`
+}
+
+// SetsProgress totals sets in the unit of a goal type: reps for "reps", minutes for "mins"
+// and kilometers for "kms"
+func SetsProgress(goalType string, sets []ExerciseSet) float64 {
+	var total float64
+	for _, set := range sets {
+		switch goalType {
+		case "reps":
+			total += float64(set.Reps)
+		case "mins":
+			total += float64(set.DurationSeconds) / 60
+		case "kms":
+			total += set.Distance
+		}
+	}
+	return total
 }
 
 // NutritionGoal represents a goal for nutrition intake.(WATER,CALORIES,CUSTOM GOALS)
 // all goals whose goalName is not water or calories are custom goals
 type NutritionGoal struct {
-	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`            // Unique identifier
-	UserID        primitive.ObjectID `bson:"userId" json:"userId"`               // Reference to the user
-	GoalName      string             `bson:"goalName" json:"goalName"`           // Name of the goal (calories/water)
-	Type          string             `bson:"type" json:"type"`                   // "kcal" "L" or "g"
-	GoalValue     float64            `bson:"goalValue" json:"goalValue"`         // Target value
-	ProgressValue float64            `bson:"progressValue" json:"progressValue"` // Current intake value
-	CreatedAt     time.Time          `bson:"createdAt" json:"createdAt"`         // Creation timestamp
-	UpdatedAt     time.Time          `bson:"updatedAt" json:"updatedAt"`         // Last update timestamp
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`                                        // Unique identifier
+	UserID        primitive.ObjectID `bson:"userId" json:"userId"`                                           // Reference to the user
+	GoalName      string             `bson:"goalName" json:"goalName" validate:"required"`                   // Name of the goal (calories/water)
+	Type          string             `bson:"type" json:"type"`                                               // "kcal" "L" or "g"
+	GoalValue     float64            `bson:"goalValue" json:"goalValue" validate:"gte=0,lte=100000"`         // Target value
+	ProgressValue float64            `bson:"progressValue" json:"progressValue" validate:"gte=0,lte=100000"` // Current intake value
+	CreatedAt     time.Time          `bson:"createdAt" json:"createdAt"`                                     // Creation timestamp
+	UpdatedAt     time.Time          `bson:"updatedAt" json:"updatedAt"`                                     // Last update timestamp
 }
 
 // WeightEntry represents a single weight measurement
 type WeightEntry struct {
-	Value float64   `bson:"value" json:"value"` // Weight value
-	Date  time.Time `bson:"date" json:"date"`   // Date of measurement
+	Value float64   `bson:"value" json:"value" validate:"gte=0,lte=1000"` // Weight value
+	Date  time.Time `bson:"date" json:"date"`                             // Date of measurement
 }
 
 // WeightGoal represents a goal for weight management
 type WeightGoal struct {
-	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`          // Unique identifier
-	UserID       primitive.ObjectID `bson:"userId" json:"userId"`             // Reference to the user
-	GoalValue    float64            `bson:"goalValue" json:"goalValue"`       // Target weight
-	GoalName     string             `bson:"goalName" json:"goalName"`         // Name of the goal
-	CurrentValue float64            `bson:"currentValue" json:"currentValue"` // Current weight
-	Unit         string             `bson:"unit" json:"unit"`                 // kg or lbs
-	Entries      []WeightEntry      `bson:"entries" json:"entries"`           // History of weight entries
-	CreatedAt    time.Time          `bson:"createdAt" json:"createdAt"`       // Creation timestamp
-	UpdatedAt    time.Time          `bson:"updatedAt" json:"updatedAt"`       // Last update timestamp
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`                                    // Unique identifier
+	UserID       primitive.ObjectID `bson:"userId" json:"userId"`                                       // Reference to the user
+	GoalValue    float64            `bson:"goalValue" json:"goalValue" validate:"gte=0,lte=1000"`       // Target weight
+	GoalName     string             `bson:"goalName" json:"goalName"`                                   // Name of the goal
+	CurrentValue float64            `bson:"currentValue" json:"currentValue" validate:"gte=0,lte=1000"` // Current weight
+	Unit         string             `bson:"unit" json:"unit" validate:"omitempty,oneof=kg lbs"`         // Stored as kg; goals created before unit conversion may be lbs
+	Entries      []WeightEntry      `bson:"entries" json:"entries" validate:"dive"`                     // History of weight entries
+	CreatedAt    time.Time          `bson:"createdAt" json:"createdAt"`                                 // Creation timestamp
+	UpdatedAt    time.Time          `bson:"updatedAt" json:"updatedAt"`                                 // Last update timestamp
 }
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// DurationRange is how long an exercise takes, in whole minutes
+type DurationRange struct {
+	MinMinutes int `bson:"minMinutes" json:"minMinutes" validate:"gt=0,lte=600"`
+	MaxMinutes int `bson:"maxMinutes" json:"maxMinutes" validate:"gtefield=MinMinutes,lte=600"`
+}
+
+// durationRangeFields avoids recursing into the custom unmarshalers
+type durationRangeFields DurationRange
+
+var durationNumber = regexp.MustCompile(`\d+(\.\d+)?`)
+
+// ParseDurationRange reads a free-text duration such as "10-15 mins", "20 minutes",
+// "1 hour" or "30-45 sec", as EstimatedTime was stored before it became a range
+func ParseDurationRange(s string) (DurationRange, error) {
+	numbers := durationNumber.FindAllString(s, 2)
+	if len(numbers) == 0 {
+		return DurationRange{}, fmt.Errorf("could not read a duration from %q", s)
+	}
+
+	lower := strings.ToLower(s)
+	scale := 1.0
+	switch {
+	case strings.Contains(lower, "sec"):
+		scale = 1.0 / 60
+	case strings.Contains(lower, "hour") || strings.Contains(lower, "hr"):
+		scale = 60
+	}
+
+	minutes := make([]int, len(numbers))
+	for i, n := range numbers {
+		value, _ := strconv.ParseFloat(n, 64)
+		// Round partial minutes up so short durations do not become zero
+		minutes[i] = int(math.Ceil(value * scale))
+	}
+
+	r := DurationRange{MinMinutes: minutes[0], MaxMinutes: minutes[0]}
+	if len(minutes) == 2 {
+		r.MaxMinutes = minutes[1]
+	}
+	if r.MaxMinutes < r.MinMinutes {
+		r.MinMinutes, r.MaxMinutes = r.MaxMinutes, r.MinMinutes
+	}
+	return r, nil
+}
+
+// UnmarshalJSON accepts either {"minMinutes":..,"maxMinutes":..} or a string like "10-15 mins"
+func (r *DurationRange) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		parsed, err := ParseDurationRange(text)
+		if err != nil {
+			return err
+		}
+		*r = parsed
+		return nil
+	}
+	var fields durationRangeFields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return errors.New("estimatedTime must be an object with minMinutes and maxMinutes, or a string like \"10-15 mins\"")
+	}
+	*r = DurationRange(fields)
+	return nil
+}
+
+// UnmarshalBSONValue reads both ranges and the strings stored by older versions.
+// Strings that cannot be read decode as an empty range rather than failing the whole document.
+func (r *DurationRange) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	switch t {
+	case bsontype.String:
+		var text string
+		if err := bson.UnmarshalValue(t, data, &text); err != nil {
+			return err
+		}
+		*r, _ = ParseDurationRange(text)
+		return nil
+	case bsontype.EmbeddedDocument:
+		var fields durationRangeFields
+		if err := bson.Unmarshal(data, &fields); err != nil {
+			return err
+		}
+		*r = DurationRange(fields)
+		return nil
+	case bsontype.Null, bsontype.Undefined:
+		*r = DurationRange{}
+		return nil
+	default:
+		return fmt.Errorf("cannot decode %v into a DurationRange", t)
+	}
+}
@@ -1,18 +1,83 @@
 package models
 
 import (
+	"strings"
+	"time"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Intensities a goal can be performed at, selecting one of an exercise's IntensityMETs
+const (
+	IntensityLight    = "light"
+	IntensityModerate = "moderate"
+	IntensityVigorous = "vigorous"
+)
+
+// Equipment locations a user can keep an equipment profile for
+const (
+	LocationHome   = "home"
+	LocationGym    = "gym"
+	LocationTravel = "travel"
+)
+
+// Relations of a curated variation to the exercise it is attached to
+const (
+	VariationEasier  = "easier"
+	VariationHarder  = "harder"
+	VariationSimilar = "similar"
+)
+
+// ExerciseVariation links an exercise to an easier, harder or similar one
+type ExerciseVariation struct {
+	ExerciseID primitive.ObjectID `bson:"exerciseId" json:"exerciseId" validate:"required"`
+	Relation   string             `bson:"relation" json:"relation" validate:"required,oneof=easier harder similar"`
+}
+
+// InverseRelation returns the relation seen from the other end of a variation link
+func InverseRelation(relation string) string {
+	switch relation {
+	case VariationEasier:
+		return VariationHarder
+	case VariationHarder:
+		return VariationEasier
+	default:
+		return relation
+	}
+}
+
 type Exercise struct {
-	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Name          string             `bson:"name" json:"name" validate:"required"`
-	Category      string             `bson:"category" json:"category" validate:"required"`
-	MainMuscles   []string           `bson:"mainMuscles" json:"mainMuscles" validate:"required"`
-	Difficulty    string             `bson:"difficulty" json:"difficulty" validate:"required"`
-	Benefits      []string           `bson:"benefits" json:"benefits" validate:"required"`
-	Steps         []string           `bson:"steps" json:"steps" validate:"required"`
-	Tips          []string           `bson:"tips" json:"tips" validate:"required"`
-	EstimatedTime string             `bson:"estimatedTime" json:"estimatedTime" validate:"required"`
-	VideoURL      string             `bson:"videoUrl" json:"videoUrl" validate:"required,url"`
+	ID            primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	OwnerID       *primitive.ObjectID `bson:"ownerId,omitempty" json:"ownerId,omitempty"` // Set on private exercises; nil for the global catalog
+	Name          string              `bson:"name" json:"name" validate:"required"`
+	Category      string              `bson:"category" json:"category" validate:"required"`
+	MainMuscles   []string            `bson:"mainMuscles" json:"mainMuscles" validate:"required,min=1,dive,required"`
+	Difficulty    string              `bson:"difficulty" json:"difficulty" validate:"required"`
+	Benefits      []string            `bson:"benefits" json:"benefits" validate:"required,min=1,dive,required"`
+	Steps         []string            `bson:"steps" json:"steps" validate:"required,min=1,dive,required"`
+	Tips          []string            `bson:"tips" json:"tips" validate:"required,min=1,dive,required"`
+	EstimatedTime DurationRange       `bson:"estimatedTime" json:"estimatedTime" validate:"required"`
+	Equipment     []string            `bson:"equipment" json:"equipment" validate:"omitempty,dive,required"` // Empty for bodyweight movements; nil or missing when unknown
+	VideoURL      string              `bson:"videoUrl" json:"videoUrl" validate:"required,url"`
+	MET           float64             `bson:"met,omitempty" json:"met,omitempty" validate:"omitempty,gt=0,lte=25"` // Metabolic equivalent, used to estimate calories burned
+	IntensityMETs map[string]float64  `bson:"intensityMets,omitempty" json:"intensityMets,omitempty" validate:"omitempty,dive,keys,oneof=light moderate vigorous,endkeys,gt=0,lte=25"`
+	Variations    []ExerciseVariation `bson:"variations,omitempty" json:"variations,omitempty" validate:"omitempty,dive"` // Curated progression links, edited by admins
+	DeletedAt     *time.Time          `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"`                             // Set when soft-deleted: hidden from the catalog but still resolvable by ID
+}
+
+// NormalizeEquipment lowercases and trims equipment names and drops blanks and duplicates,
+// so exercises and equipment profiles compare reliably. An empty list is returned as an empty
+// slice, so bodyweight exercises store [] rather than nothing.
+func NormalizeEquipment(items []string) []string {
+	normalized := []string{}
+	seen := map[string]bool{}
+	for _, item := range items {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item == "" || seen[item] {
+			continue
+		}
+		seen[item] = true
+		normalized = append(normalized, item)
+	}
+	return normalized
 }
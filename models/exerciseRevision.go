@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Revision actions
+const (
+	RevisionBaseline = "baseline" // State of a guide that existed before revisions were recorded
+	RevisionCreate   = "create"
+	RevisionUpdate   = "update"
+	RevisionRollback = "rollback"
+)
+
+// ExerciseRevision is a snapshot of an exercise guide after a change
+type ExerciseRevision struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ExerciseID     primitive.ObjectID `bson:"exerciseId" json:"exerciseId"`
+	Revision       int                `bson:"revision" json:"revision"` // Sequence number starting at 1
+	Action         string             `bson:"action" json:"action"`
+	ChangedFields  []string           `bson:"changedFields" json:"changedFields"`
+	ChangedBy      primitive.ObjectID `bson:"changedBy,omitempty" json:"changedBy,omitempty"`
+	ChangedAt      time.Time          `bson:"changedAt" json:"changedAt"`
+	RolledBackFrom int                `bson:"rolledBackFrom,omitempty" json:"rolledBackFrom,omitempty"` // Revision restored by a rollback
+	Snapshot       Exercise           `bson:"snapshot" json:"snapshot"`
+}
@@ -9,19 +9,22 @@ import (
 // FoodItem represents a single food item with its nutritional information
 type FoodItem struct {
 	ID                  primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Name                string             `bson:"name" json:"name"`
+	Name                string             `bson:"name" json:"name" validate:"required"`
 	ConsumedAt          time.Time          `bson:"consumedAt" json:"consumedAt"`
-	ServingSizeG        float64            `bson:"serving_size_g" json:"serving_size_g"`
-	Calories            float64            `bson:"calories" json:"calories"`
-	FatTotalG           float64            `bson:"fat_total_g" json:"fat_total_g"`
-	FatSaturatedG       float64            `bson:"fat_saturated_g" json:"fat_saturated_g"`
-	ProteinG            float64            `bson:"protein_g" json:"protein_g"`
-	SodiumMg            float64            `bson:"sodium_mg" json:"sodium_mg"`
-	PotassiumMg         float64            `bson:"potassium_mg" json:"potassium_mg"`
-	CholesterolMg       float64            `bson:"cholesterol_mg" json:"cholesterol_mg"`
-	CarbohydratesTotalG float64            `bson:"carbohydrates_total_g" json:"carbohydrates_total_g"`
-	FiberG              float64            `bson:"fiber_g" json:"fiber_g"`
-	SugarG              float64            `bson:"sugar_g" json:"sugar_g"`
+	ConsumedDate        time.Time          `bson:"consumedDate" json:"consumedDate"` // Calendar date of ConsumedAt in the user's time zone
+	ServingSizeG        float64            `bson:"serving_size_g" json:"serving_size_g" validate:"gte=0,lte=5000"`
+	ServingSize         float64            `bson:"-" json:"serving_size,omitempty" validate:"gte=0"`                // Serving size in ServingUnit, not stored
+	ServingUnit         string             `bson:"-" json:"serving_unit,omitempty" validate:"omitempty,oneof=g oz"` // "g" or "oz"
+	Calories            float64            `bson:"calories" json:"calories" validate:"gte=0,lte=10000"`
+	FatTotalG           float64            `bson:"fat_total_g" json:"fat_total_g" validate:"gte=0,lte=1000"`
+	FatSaturatedG       float64            `bson:"fat_saturated_g" json:"fat_saturated_g" validate:"gte=0,lte=1000"`
+	ProteinG            float64            `bson:"protein_g" json:"protein_g" validate:"gte=0,lte=1000"`
+	SodiumMg            float64            `bson:"sodium_mg" json:"sodium_mg" validate:"gte=0,lte=100000"`
+	PotassiumMg         float64            `bson:"potassium_mg" json:"potassium_mg" validate:"gte=0,lte=100000"`
+	CholesterolMg       float64            `bson:"cholesterol_mg" json:"cholesterol_mg" validate:"gte=0,lte=100000"`
+	CarbohydratesTotalG float64            `bson:"carbohydrates_total_g" json:"carbohydrates_total_g" validate:"gte=0,lte=1000"`
+	FiberG              float64            `bson:"fiber_g" json:"fiber_g" validate:"gte=0,lte=1000"`
+	SugarG              float64            `bson:"sugar_g" json:"sugar_g" validate:"gte=0,lte=1000"`
 }
 
 // FoodConsumed represents the collection of food items consumed by a user
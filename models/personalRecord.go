@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Kinds of personal record kept per exercise
+const (
+	RecordMaxWeight       = "maxWeight"       // Heaviest set, in kg
+	RecordMaxReps         = "maxReps"         // Most reps in one set
+	RecordEstimated1RM    = "estimated1RM"    // Best estimated one-rep max, in kg
+	RecordFastestPace     = "fastestPace"     // Quickest minutes per km; lower is better
+	RecordLongestDistance = "longestDistance" // Longest single effort, in kms
+	RecordDailyReps       = "dailyReps"       // Most reps of the exercise in one day
+	RecordDailyDistance   = "dailyDistance"   // Longest total distance in one day, in kms
+)
+
+// RecordUnits gives the unit each kind of record is stored in
+var RecordUnits = map[string]string{
+	RecordMaxWeight:       "kg",
+	RecordMaxReps:         "reps",
+	RecordEstimated1RM:    "kg",
+	RecordFastestPace:     "min/km",
+	RecordLongestDistance: "kms",
+	RecordDailyReps:       "reps",
+	RecordDailyDistance:   "kms",
+}
+
+// LowerIsBetter reports whether a smaller value beats the record, as with pace
+func LowerIsBetter(kind string) bool {
+	return kind == RecordFastestPace
+}
+
+// PersonalRecord is a user's best performance of one kind on an exercise, with every
+// earlier best it replaced
+type PersonalRecord struct {
+	ID         primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID  `bson:"userId" json:"userId"`
+	ExerciseID primitive.ObjectID  `bson:"exerciseId" json:"exerciseId"`
+	Kind       string              `bson:"kind" json:"kind"`
+	Value      float64             `bson:"value" json:"value"`
+	Unit       string              `bson:"unit" json:"unit"`
+	Date       time.Time           `bson:"date" json:"date"`                       // Day the record was set
+	GoalID     primitive.ObjectID  `bson:"goalId" json:"goalId"`                   // Goal it was recorded on
+	SetID      *primitive.ObjectID `bson:"setId,omitempty" json:"setId,omitempty"` // Set it was recorded on, if any
+	AchievedAt time.Time           `bson:"achievedAt" json:"achievedAt"`
+	History    []RecordEntry       `bson:"history" json:"history"` // Every best so far, oldest first, ending with the current one
+}
+
+// RecordEntry is one time a personal record was set
+type RecordEntry struct {
+	Value      float64             `bson:"value" json:"value"`
+	Date       time.Time           `bson:"date" json:"date"`
+	GoalID     primitive.ObjectID  `bson:"goalId" json:"goalId"`
+	SetID      *primitive.ObjectID `bson:"setId,omitempty" json:"setId,omitempty"`
+	AchievedAt time.Time           `bson:"achievedAt" json:"achievedAt"`
+}
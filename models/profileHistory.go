@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ProfileHistoryEntry records a change to a body metric on the user profile
+type ProfileHistoryEntry struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID        primitive.ObjectID `bson:"userId" json:"userId"`
+	Field         string             `bson:"field" json:"field"`                 // "weight" or "height"
+	Value         float64            `bson:"value" json:"value"`                 // New value
+	PreviousValue float64            `bson:"previousValue" json:"previousValue"` // Value before the change, 0 if unset
+	RecordedAt    time.Time          `bson:"recordedAt" json:"recordedAt"`
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Program is a multi-week training plan, such as an 8-week 5x5, with a weekly schedule of
+// workouts. Coaches and admins create programs; any user can enroll.
+type Program struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name        string             `bson:"name" json:"name" validate:"required,max=100"`
+	Description string             `bson:"description,omitempty" json:"description,omitempty" validate:"max=2000"`
+	Weeks       int                `bson:"weeks" json:"weeks" validate:"gte=1,lte=52"`
+	Workouts    []ProgramWorkout   `bson:"workouts" json:"workouts" validate:"required,min=1,max=7,dive"` // At most one per day of the week
+	CreatedBy   primitive.ObjectID `bson:"createdBy" json:"createdBy"`
+	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt   time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// ProgramWorkout is the workout done on one day of each program week
+type ProgramWorkout struct {
+	Day       int               `bson:"day" json:"day" validate:"gte=1,lte=7"` // Day of the program week; day 1 is the weekday the user started on
+	Name      string            `bson:"name" json:"name" validate:"required,max=100"`
+	Exercises []ProgramExercise `bson:"exercises" json:"exercises" validate:"required,min=1,max=50,dive"`
+}
+
+// ProgramExercise is an exercise in a program workout. Its target grows each week by
+// WeeklyIncrease, unless WeeklyTargets lists the target for that week explicitly.
+type ProgramExercise struct {
+	TemplateExercise `bson:",inline"`
+	WeeklyIncrease   float64   `bson:"weeklyIncrease,omitempty" json:"weeklyIncrease,omitempty" validate:"gte=0,lte=10000"`
+	WeeklyTargets    []float64 `bson:"weeklyTargets,omitempty" json:"weeklyTargets,omitempty" validate:"max=52,dive,gt=0,lte=100000"` // Index 0 is week 1
+}
+
+// TargetFor returns the exercise's target in a program week, starting at 1
+func (pe ProgramExercise) TargetFor(week int) float64 {
+	if week >= 1 && week <= len(pe.WeeklyTargets) {
+		return pe.WeeklyTargets[week-1]
+	}
+	return pe.GoalValue + pe.WeeklyIncrease*float64(week-1)
+}
+
+// WorkoutOn returns the workout scheduled on a day of the program week, if any
+func (p Program) WorkoutOn(day int) (ProgramWorkout, bool) {
+	for _, w := range p.Workouts {
+		if w.Day == day {
+			return w, true
+		}
+	}
+	return ProgramWorkout{}, false
+}
+
+// Enrollment states
+const (
+	EnrollmentActive    = "active"
+	EnrollmentPaused    = "paused"
+	EnrollmentCompleted = "completed"
+	EnrollmentCancelled = "cancelled"
+)
+
+// Enrollment is a user following a program from a start date. Dates are calendar dates
+// stored as midnight UTC, like daily_data dates.
+type Enrollment struct {
+	ID                primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID            primitive.ObjectID `bson:"userId" json:"userId"`
+	ProgramID         primitive.ObjectID `bson:"programId" json:"programId"`
+	StartDate         time.Time          `bson:"startDate" json:"startDate"`
+	Status            string             `bson:"status" json:"status"`
+	Pauses            []EnrollmentPause  `bson:"pauses" json:"pauses"`
+	EndDate           *time.Time         `bson:"endDate,omitempty" json:"endDate,omitempty"` // First day no longer scheduled, once cancelled
+	MaterializedDates []time.Time        `bson:"materializedDates" json:"-"`                 // Dates whose goals have been created
+	CreatedAt         time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt         time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// EnrollmentPause is a stretch of days on which the program is suspended. The schedule
+// resumes where it left off, so every later workout moves back by the pause's length.
+// End is exclusive and nil while the pause is open.
+type EnrollmentPause struct {
+	Start time.Time  `bson:"start" json:"start"`
+	End   *time.Time `bson:"end,omitempty" json:"end,omitempty"`
+}
+
+// ProgramDay returns how many program days have elapsed before date (0 on the first day),
+// not counting paused days. It reports false before the start date and on paused days.
+// A pause that began before the start date only holds back the days from the start on.
+func (e Enrollment) ProgramDay(date time.Time) (int, bool) {
+	if date.Before(e.StartDate) {
+		return 0, false
+	}
+	elapsed := daysBetween(e.StartDate, date)
+	for _, pause := range e.Pauses {
+		if !pause.Start.After(date) && (pause.End == nil || date.Before(*pause.End)) {
+			return 0, false
+		}
+		if pause.End == nil || pause.End.After(date) {
+			continue
+		}
+		start := pause.Start
+		if start.Before(e.StartDate) {
+			start = e.StartDate
+		}
+		if pause.End.After(start) {
+			elapsed -= daysBetween(start, *pause.End)
+		}
+	}
+	return elapsed, true
+}
+
+// IsMaterialized reports whether the goals for date have been created
+func (e Enrollment) IsMaterialized(date time.Time) bool {
+	for _, d := range e.MaterializedDates {
+		if d.Equal(date) {
+			return true
+		}
+	}
+	return false
+}
+
+// daysBetween counts whole days from one calendar date to another
+func daysBetween(from, to time.Time) int {
+	return int(to.Sub(from).Hours() / 24)
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEnrollmentProgramDay(t *testing.T) {
+	day := func(n int) time.Time { return time.Date(2024, 1, n, 0, 0, 0, 0, time.UTC) }
+	until := func(start, end int) EnrollmentPause {
+		e := day(end)
+		return EnrollmentPause{Start: day(start), End: &e}
+	}
+
+	tests := []struct {
+		name   string
+		pauses []EnrollmentPause
+		date   time.Time
+		want   int
+		wantOK bool
+	}{
+		{name: "before the start date", date: day(9), want: 0, wantOK: false},
+		{name: "start date", date: day(10), want: 0, wantOK: true},
+		{name: "no pauses", date: day(15), want: 5, wantOK: true},
+		{name: "first day of a pause", pauses: []EnrollmentPause{until(12, 14)}, date: day(12), wantOK: false},
+		{name: "last day of a pause", pauses: []EnrollmentPause{until(12, 14)}, date: day(13), wantOK: false},
+		{name: "day a pause ends", pauses: []EnrollmentPause{until(12, 14)}, date: day(14), want: 2, wantOK: true},
+		{name: "after a pause", pauses: []EnrollmentPause{until(12, 14)}, date: day(20), want: 8, wantOK: true},
+		{name: "before a later pause", pauses: []EnrollmentPause{until(20, 25)}, date: day(15), want: 5, wantOK: true},
+		{name: "during an open pause", pauses: []EnrollmentPause{{Start: day(12)}}, date: day(20), wantOK: false},
+		{name: "before an open pause", pauses: []EnrollmentPause{{Start: day(12)}}, date: day(11), want: 1, wantOK: true},
+		{name: "several pauses", pauses: []EnrollmentPause{until(12, 14), until(16, 19)}, date: day(20), want: 5, wantOK: true},
+		{name: "between several pauses", pauses: []EnrollmentPause{until(12, 14), until(16, 19)}, date: day(17), wantOK: false},
+		{name: "pause spanning the start date", pauses: []EnrollmentPause{until(5, 15)}, date: day(12), wantOK: false},
+		{name: "day a pause spanning the start date ends", pauses: []EnrollmentPause{until(5, 15)}, date: day(15), want: 0, wantOK: true},
+		{name: "after a pause spanning the start date", pauses: []EnrollmentPause{until(5, 15)}, date: day(17), want: 2, wantOK: true},
+		{name: "open pause from before the start date", pauses: []EnrollmentPause{{Start: day(5)}}, date: day(12), wantOK: false},
+		{name: "pause ending on the start date", pauses: []EnrollmentPause{until(5, 10)}, date: day(12), want: 2, wantOK: true},
+		{name: "pause ending before the start date", pauses: []EnrollmentPause{until(3, 6)}, date: day(12), want: 2, wantOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enrollment := Enrollment{StartDate: day(10), Pauses: tt.pauses}
+			got, ok := enrollment.ProgramDay(tt.date)
+			if ok != tt.wantOK || (ok && got != tt.want) {
+				t.Errorf("ProgramDay(%s) = %d, %v, want %d, %v", tt.date.Format("2006-01-02"), got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
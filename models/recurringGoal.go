@@ -0,0 +1,119 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Recurrence frequencies
+const (
+	RecurDaily    = "daily"    // Every day
+	RecurWeekdays = "weekdays" // On the listed days of the week
+	RecurInterval = "interval" // Every N days
+)
+
+// RecurringGoal is a goal repeated on a schedule, such as drinking 3L of water every day.
+// Its goal is copied into daily_data the first time each scheduled day is read or written.
+// Edits add a version that takes effect on a later date, so days already past keep the
+// goal they had. Dates are calendar dates stored as midnight UTC, like daily_data dates.
+type RecurringGoal struct {
+	ID                primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	UserID            primitive.ObjectID     `bson:"userId" json:"userId"`
+	Kind              string                 `bson:"kind" json:"kind"` // Goal kind as in CreateGoal: exercise, water, calorie or customgoal
+	StartDate         time.Time              `bson:"startDate" json:"startDate"`
+	EndDate           *time.Time             `bson:"endDate,omitempty" json:"endDate,omitempty"` // First day no longer scheduled, once stopped
+	Versions          []RecurringGoalVersion `bson:"versions" json:"versions"`                   // Oldest first
+	MaterializedDates []time.Time            `bson:"materializedDates" json:"-"`                 // Dates whose goal has been created
+	CreatedAt         time.Time              `bson:"createdAt" json:"createdAt"`
+	UpdatedAt         time.Time              `bson:"updatedAt" json:"updatedAt"`
+}
+
+// RecurringGoalVersion is the schedule and goal of a recurring goal from EffectiveFrom on
+type RecurringGoalVersion struct {
+	EffectiveFrom time.Time          `bson:"effectiveFrom" json:"effectiveFrom"`
+	Frequency     string             `bson:"frequency" json:"frequency" validate:"required,oneof=daily weekdays interval"`
+	Weekdays      []int              `bson:"weekdays,omitempty" json:"weekdays,omitempty" validate:"max=7,unique,dive,gte=0,lte=6"` // 0 is Sunday
+	Interval      int                `bson:"interval,omitempty" json:"interval,omitempty" validate:"gte=0,lte=365"`                 // Days between occurrences, counted from Anchor
+	Anchor        *time.Time         `bson:"anchor,omitempty" json:"anchor,omitempty"`                                              // An occurrence of an interval schedule; EffectiveFrom when unset
+	GoalName      string             `bson:"goalName" json:"goalName" validate:"required,max=100"`
+	Type          string             `bson:"type" json:"type" validate:"required"` // Unit of the goal, such as reps, kms or L
+	GoalValue     float64            `bson:"goalValue" json:"goalValue" validate:"gt=0,lte=100000"`
+	ExerciseID    primitive.ObjectID `bson:"exerciseId,omitempty" json:"exerciseId,omitempty"` // Exercise goals only
+	Intensity     string             `bson:"intensity,omitempty" json:"intensity,omitempty" validate:"omitempty,oneof=light moderate vigorous"`
+	Comments      string             `bson:"comments,omitempty" json:"comments,omitempty" validate:"max=500"`
+}
+
+// VersionOn returns the version in effect on date, if the series is running then
+func (r RecurringGoal) VersionOn(date time.Time) (RecurringGoalVersion, bool) {
+	if date.Before(r.StartDate) || (r.EndDate != nil && !date.Before(*r.EndDate)) {
+		return RecurringGoalVersion{}, false
+	}
+	for i := len(r.Versions) - 1; i >= 0; i-- {
+		if !r.Versions[i].EffectiveFrom.After(date) {
+			return r.Versions[i], true
+		}
+	}
+	return RecurringGoalVersion{}, false
+}
+
+// OccursOn reports whether the goal is scheduled on date and returns the version that applies
+func (r RecurringGoal) OccursOn(date time.Time) (RecurringGoalVersion, bool) {
+	version, ok := r.VersionOn(date)
+	if !ok {
+		return version, false
+	}
+	return version, version.OccursOn(date)
+}
+
+// OccursOn reports whether a version's schedule includes date
+func (v RecurringGoalVersion) OccursOn(date time.Time) bool {
+	switch v.Frequency {
+	case RecurDaily:
+		return true
+	case RecurWeekdays:
+		for _, day := range v.Weekdays {
+			if time.Weekday(day) == date.Weekday() {
+				return true
+			}
+		}
+		return false
+	case RecurInterval:
+		anchor := v.EffectiveFrom
+		if v.Anchor != nil {
+			anchor = *v.Anchor
+		}
+		return v.Interval > 0 && daysBetween(anchor, date)%v.Interval == 0
+	default:
+		return false
+	}
+}
+
+// Goal builds the daily goal a version schedules: an ExerciseGoal for exercise goals and a
+// NutritionGoal otherwise
+func (v RecurringGoalVersion) Goal(kind string, userID primitive.ObjectID, now time.Time) interface{} {
+	if kind == "exercise" {
+		return ExerciseGoal{
+			ID:         primitive.NewObjectID(),
+			UserID:     userID,
+			ExerciseID: v.ExerciseID,
+			GoalName:   v.GoalName,
+			Type:       v.Type,
+			GoalValue:  v.GoalValue,
+			Intensity:  v.Intensity,
+			Comments:   v.Comments,
+			IsActive:   true,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		}
+	}
+	return NutritionGoal{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		GoalName:  v.GoalName,
+		Type:      v.Type,
+		GoalValue: v.GoalValue,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
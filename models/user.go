@@ -1,14 +1,69 @@
 package models
 
 import (
+	"time"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// User roles, ordered from least to most privileged
+const (
+	RoleUser  = "user"
+	RoleCoach = "coach"
+	RoleAdmin = "admin"
+)
+
 type User struct {
 	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	Email    string             `bson:"email" json:"email" validate:"required,email"`
 	Name     string             `bson:"name" json:"name" validate:"required"`
-	Password string             `bson:"password" json:"password" validate:"required"`
-	Weight   float64            `bson:"weight" json:"weight"`
-	Height   float64            `bson:"height" json:"height"`
+	Password string             `bson:"password" json:"password" validate:"required,min=8"`
+	Role     string             `bson:"role,omitempty" json:"role"`                    // "user", "coach" or "admin"
+	Verified bool               `bson:"verified" json:"verified"`                      // Email address has been confirmed
+	Weight   float64            `bson:"weight" json:"weight" validate:"gte=0,lte=500"` // kg
+	Height   float64            `bson:"height" json:"height" validate:"gte=0,lte=300"` // cm
+
+	BirthDate     *time.Time `bson:"birthDate,omitempty" json:"birthDate,omitempty"`
+	Sex           string     `bson:"sex,omitempty" json:"sex,omitempty"`                     // "male", "female" or "other"
+	BodyFat       float64    `bson:"bodyFat,omitempty" json:"bodyFat,omitempty"`             // Body fat percentage, 0 if unknown
+	ActivityLevel string     `bson:"activityLevel,omitempty" json:"activityLevel,omitempty"` // "sedentary" through "very_active"
+	Units         string     `bson:"units,omitempty" json:"units,omitempty"`                 // "metric" or "imperial"
+	Timezone      string     `bson:"timezone,omitempty" json:"timezone,omitempty"`           // IANA name, e.g. "Asia/Kolkata"
+	UpdatedAt     *time.Time `bson:"updatedAt,omitempty" json:"updatedAt,omitempty"`
+
+	// EquipmentProfiles lists the equipment available at each location ("home", "gym", "travel")
+	EquipmentProfiles map[string][]string `bson:"equipmentProfiles,omitempty" json:"equipmentProfiles,omitempty"`
+}
+
+// Activity levels used for energy expenditure estimates
+const (
+	ActivitySedentary  = "sedentary"
+	ActivityLight      = "light"
+	ActivityModerate   = "moderate"
+	ActivityActive     = "active"
+	ActivityVeryActive = "very_active"
+)
+
+// Preferred unit systems
+const (
+	UnitsMetric   = "metric"
+	UnitsImperial = "imperial"
+)
+
+// EffectiveRole returns the user's role, treating accounts created before roles existed as regular users
+func (u User) EffectiveRole() string {
+	if u.Role == "" {
+		return RoleUser
+	}
+	return u.Role
+}
+
+// IsValidLocation reports whether location is one of the equipment profile locations
+func IsValidLocation(location string) bool {
+	return location == LocationHome || location == LocationGym || location == LocationTravel
+}
+
+// IsValidRole reports whether role is one of the known roles
+func IsValidRole(role string) bool {
+	return role == RoleUser || role == RoleCoach || role == RoleAdmin
 }
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Workout session states
+const (
+	SessionActive    = "active"
+	SessionFinished  = "finished"
+	SessionAbandoned = "abandoned"
+)
+
+// WorkoutSession is a workout being performed, timed from start to finish. Everything
+// logged is saved as it happens, so a client that disconnects can resume the session.
+// Finishing it writes the performed sets into the ExerciseGoals of its date.
+type WorkoutSession struct {
+	ID              primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	UserID          primitive.ObjectID   `bson:"userId" json:"userId"`
+	Name            string               `bson:"name,omitempty" json:"name,omitempty" validate:"max=100"`
+	Date            time.Time            `bson:"date" json:"date"` // Day whose goals receive the results
+	Status          string               `bson:"status" json:"status"`
+	Exercises       []SessionExercise    `bson:"exercises" json:"exercises"`
+	Rests           []SessionRest        `bson:"rests" json:"rests"`
+	StartedAt       time.Time            `bson:"startedAt" json:"startedAt"`
+	LastActivityAt  time.Time            `bson:"lastActivityAt" json:"lastActivityAt"`
+	FinishedAt      *time.Time           `bson:"finishedAt,omitempty" json:"finishedAt,omitempty"`
+	DurationSeconds int                  `bson:"durationSeconds" json:"durationSeconds"`     // Start to finish, once finished
+	RestSeconds     int                  `bson:"restSeconds" json:"restSeconds"`             // Total of the rest periods
+	GoalIDs         []primitive.ObjectID `bson:"goalIds,omitempty" json:"goalIds,omitempty"` // Goals the results were written to
+	Unsaved         []string             `bson:"unsaved,omitempty" json:"unsaved,omitempty"` // Goal names whose day goal could not take the sets
+}
+
+// SessionExercise is an exercise performed in a session with the sets done so far
+type SessionExercise struct {
+	ID         primitive.ObjectID `bson:"_id" json:"id"`
+	ExerciseID primitive.ObjectID `bson:"exerciseId" json:"exerciseId"`
+	GoalName   string             `bson:"goalName" json:"goalName" validate:"required,max=100"`     // Goal the sets are written to
+	Type       string             `bson:"type" json:"type" validate:"required,oneof=reps mins kms"` // Type of that goal if it has to be created
+	Intensity  string             `bson:"intensity,omitempty" json:"intensity,omitempty" validate:"omitempty,oneof=light moderate vigorous"`
+	Sets       []ExerciseSet      `bson:"sets" json:"sets"`
+	AddedAt    time.Time          `bson:"addedAt" json:"addedAt"`
+}
+
+// SessionRest is a rest period taken during a session
+type SessionRest struct {
+	ID              primitive.ObjectID `bson:"_id" json:"id"`
+	DurationSeconds int                `bson:"durationSeconds" json:"durationSeconds" validate:"gte=1,lte=3600"`
+	LoggedAt        time.Time          `bson:"loggedAt" json:"loggedAt"`
+}
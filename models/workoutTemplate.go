@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WorkoutTemplate is a reusable list of exercise goals, such as a "Push day", that can be
+// applied to any date
+type WorkoutTemplate struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID      primitive.ObjectID `bson:"userId" json:"userId"`
+	Name        string             `bson:"name" json:"name" validate:"required,max=100"`
+	Description string             `bson:"description,omitempty" json:"description,omitempty" validate:"max=500"`
+	Exercises   []TemplateExercise `bson:"exercises" json:"exercises" validate:"required,min=1,max=50,dive"` // In the order they are performed
+	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt   time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// TemplateExercise is one exercise of a template with its target. Targets in kms are
+// stored in canonical units like ExerciseGoal values.
+type TemplateExercise struct {
+	ExerciseID primitive.ObjectID `bson:"exerciseId" json:"exerciseId"`                             // Linked exercise, NilObjectID if none
+	GoalName   string             `bson:"goalName" json:"goalName" validate:"required"`             // Name of the goal created for it
+	Type       string             `bson:"type" json:"type" validate:"required,oneof=reps mins kms"` // "reps", "mins", or "kms"
+	GoalValue  float64            `bson:"goalValue" json:"goalValue" validate:"gt=0,lte=100000"`    // Target for the goal
+	Intensity  string             `bson:"intensity,omitempty" json:"intensity,omitempty" validate:"omitempty,oneof=light moderate vigorous"`
+	Comments   string             `bson:"comments,omitempty" json:"comments,omitempty" validate:"max=500"`
+}
+
+// Goal builds the ExerciseGoal this template exercise creates for a user
+func (te TemplateExercise) Goal(userID primitive.ObjectID, now time.Time) ExerciseGoal {
+	return ExerciseGoal{
+		ID:         primitive.NewObjectID(),
+		UserID:     userID,
+		ExerciseID: te.ExerciseID,
+		GoalName:   te.GoalName,
+		Type:       te.Type,
+		GoalValue:  te.GoalValue,
+		Intensity:  te.Intensity,
+		Comments:   te.Comments,
+		IsActive:   true,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+}
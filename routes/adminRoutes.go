@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"fitness-backend/controllers"
+	"fitness-backend/middleware"
+	"fitness-backend/models"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// RegisterAdminRoutes sets up the admin-only routes
+func RegisterAdminRoutes(e *echo.Echo, db *mongo.Database) {
+	// Controllers
+	authController := controllers.NewAuthController(db)
+	exerciseGuideController := controllers.NewExerciseGuideController(db)
+
+	// Protected admin routes
+	admin := e.Group("/api/admin")
+	admin.Use(middleware.AuthMiddleware(db))
+	admin.Use(middleware.RequireRole(models.RoleAdmin))
+
+	admin.PATCH("/users/:id/role", authController.UpdateUserRole)
+
+	// Bulk catalog import (JSON or CSV, ?dryRun=true to preview)
+	admin.POST("/exercises/import", exerciseGuideController.ImportExercises)
+}
@@ -3,6 +3,7 @@ package routes
 import (
 	"fitness-backend/controllers"
 	"fitness-backend/middleware"
+	"fitness-backend/models"
 
 	"github.com/labstack/echo/v4"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -18,24 +19,47 @@ func RegisterExerciseRoutes(e *echo.Echo, db *mongo.Database) {
 
 	// Protected API routes
 	api := e.Group("/api")
-	api.Use(middleware.AuthMiddleware)
+	api.Use(middleware.AuthMiddleware(db))
 
 	api.GET("/getuser", authController.GetUserDetails)
 
 	// Exercise Guide Routes
 	exercise := api.Group("/exercises")
 	exercise.GET("", exerciseGuideController.GetExercises)
+	exercise.GET("/search", exerciseGuideController.SearchExercises)
 
 	// Category-based routes
 	categoryGroup := exercise.Group("/category")
 	categoryGroup.GET("/:category", exerciseGuideController.GetExercisesByCategory)
 
+	// Private exercises, managed by their owner
+	custom := exercise.Group("/custom")
+	custom.POST("", exerciseGuideController.CreateCustomExercise)
+	custom.PATCH("/:id", exerciseGuideController.PatchCustomExercise)
+	custom.DELETE("/:id", exerciseGuideController.DeleteCustomExercise)
+
 	// Exercise CRUD routes
 	exercise.GET("/:id", exerciseGuideController.GetExerciseByID)
-	exercise.POST("", exerciseGuideController.CreateExercise)
-	exercise.DELETE("/:id", exerciseGuideController.DeleteExerciseByID)
+	exercise.GET("/:id/alternatives", exerciseGuideController.GetExerciseAlternatives)
+	// ?rule=linear|double with optional increments, rep range and deload settings
+	exercise.GET("/:id/next-target", exerciseGuideController.GetNextTarget)
+	// Global catalog mutations are admin-only
+	adminOnly := middleware.RequireRole(models.RoleAdmin)
+	exercise.POST("", exerciseGuideController.CreateExercise, adminOnly)
+	// DELETE accepts ?mode=block|soft|reassign (&target=) for guides that goals still reference
+	exercise.DELETE("/:id", exerciseGuideController.DeleteExerciseByID, adminOnly)
+	exercise.PUT("/:id", exerciseGuideController.ReplaceExercise, adminOnly)
+	exercise.PATCH("/:id", exerciseGuideController.PatchExercise, adminOnly)
+	exercise.POST("/:id/restore", exerciseGuideController.RestoreExercise, adminOnly)
+	exercise.PUT("/:id/variations", exerciseGuideController.UpdateExerciseVariations, adminOnly)
+
+	// Revision history
+	exercise.GET("/:id/revisions", exerciseGuideController.GetExerciseRevisions, adminOnly)
+	exercise.GET("/:id/revisions/:revision", exerciseGuideController.GetExerciseRevision, adminOnly)
+	exercise.POST("/:id/revisions/:revision/rollback", exerciseGuideController.RollbackExercise, adminOnly)
 
 	// Goal Management Routes
+	// :date accepts YYYY-MM-DD or "today", resolved in the user's time zone
 	goals := api.Group("/goals")
 	goals.POST("/:date", goalController.CreateGoal) // Create
 	goals.GET("/:date/:id", goalController.GetGoal) // Specific GET
@@ -44,6 +68,15 @@ func RegisterExerciseRoutes(e *echo.Echo, db *mongo.Database) {
 	goals.GET("/:date", goalController.GetAllGoals)       // Get all
 	goals.DELETE("/:date/:id", goalController.DeleteGoal) // Delete
 	goals.PUT("/:date/:goalName", goalController.UpsertGoalByName)
+	// Sets take weight and distance in the user's units; the goal's progress is their total
+	goals.POST("/:date/:id/sets", goalController.AddSet)
+	goals.PUT("/:date/:id/sets/:setId", goalController.UpdateSet)
+	goals.DELETE("/:date/:id/sets/:setId", goalController.DeleteSet)
+
+	// Personal records, updated as exercise progress is logged
+	records := api.Group("/records")
+	records.GET("", goalController.GetRecords)
+	records.DELETE("/:id", goalController.DeleteRecord)
 
 	// Progress Management Routes
 	progress := api.Group("/progress")
@@ -15,7 +15,7 @@ func RegisterFoodRoutes(e *echo.Echo, db *mongo.Database) {
 
 	// Protected API routes
 	api := e.Group("/api")
-	api.Use(middleware.AuthMiddleware)
+	api.Use(middleware.AuthMiddleware(db))
 
 	// Food routes
 	api.POST("/food", foodController.AddFoodItem)
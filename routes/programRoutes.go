@@ -0,0 +1,40 @@
+package routes
+
+import (
+	"fitness-backend/controllers"
+	"fitness-backend/middleware"
+	"fitness-backend/models"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// RegisterProgramRoutes sets up the training program and enrollment routes
+func RegisterProgramRoutes(e *echo.Echo, db *mongo.Database) {
+	// Controllers
+	programController := controllers.NewProgramController(db)
+
+	// Protected API routes
+	api := e.Group("/api")
+	api.Use(middleware.AuthMiddleware(db))
+
+	programs := api.Group("/programs")
+	programs.GET("", programController.GetPrograms)
+	programs.GET("/:id", programController.GetProgram)
+	// Coaches and admins write programs; only the author or an admin may change one
+	coachOnly := middleware.RequireRole(models.RoleCoach, models.RoleAdmin)
+	programs.POST("", programController.CreateProgram, coachOnly)
+	programs.PUT("/:id", programController.UpdateProgram, coachOnly)
+	programs.DELETE("/:id", programController.DeleteProgram, coachOnly)
+	programs.POST("/:id/enroll", programController.Enroll)
+
+	enrollments := api.Group("/enrollments")
+	enrollments.GET("", programController.GetEnrollments)
+	enrollments.GET("/:id", programController.GetEnrollment)
+	// ?from= and ?to= accept YYYY-MM-DD or "today"
+	enrollments.GET("/:id/schedule", programController.GetEnrollmentSchedule)
+	enrollments.POST("/:id/pause", programController.PauseEnrollment)
+	enrollments.POST("/:id/resume", programController.ResumeEnrollment)
+	enrollments.POST("/:id/reschedule", programController.RescheduleEnrollment)
+	enrollments.DELETE("/:id", programController.CancelEnrollment)
+}
@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"fitness-backend/controllers"
+	"fitness-backend/middleware"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// RegisterRecurringGoalRoutes sets up the recurring goal routes
+func RegisterRecurringGoalRoutes(e *echo.Echo, db *mongo.Database) {
+	// Controllers
+	recurringGoalController := controllers.NewRecurringGoalController(db)
+
+	// Protected API routes
+	api := e.Group("/api")
+	api.Use(middleware.AuthMiddleware(db))
+
+	// Goals are created in daily_data the first time a scheduled day is read or written;
+	// edits and deletes apply from tomorrow on
+	recurring := api.Group("/recurring-goals")
+	recurring.POST("", recurringGoalController.CreateRecurringGoal)
+	recurring.GET("", recurringGoalController.GetRecurringGoals) // ?active=true
+	recurring.GET("/:id", recurringGoalController.GetRecurringGoal)
+	recurring.PUT("/:id", recurringGoalController.UpdateRecurringGoal)
+	recurring.DELETE("/:id", recurringGoalController.DeleteRecurringGoal)
+}
@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"fitness-backend/controllers"
+	"fitness-backend/middleware"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// RegisterSessionRoutes sets up the live workout session routes
+func RegisterSessionRoutes(e *echo.Echo, db *mongo.Database) {
+	// Controllers
+	sessionController := controllers.NewSessionController(db)
+
+	// Protected API routes
+	api := e.Group("/api")
+	api.Use(middleware.AuthMiddleware(db))
+
+	sessions := api.Group("/sessions")
+	sessions.POST("", sessionController.StartSession)
+	sessions.GET("", sessionController.GetSessions)
+	// Registered before /:id so "active" is not read as a session ID
+	sessions.GET("/active", sessionController.GetActiveSession)
+	sessions.GET("/:id", sessionController.GetSession)
+	sessions.POST("/:id/exercises", sessionController.AddSessionExercise)
+	sessions.POST("/:id/exercises/:exerciseId/sets", sessionController.LogSessionSet)
+	sessions.POST("/:id/rests", sessionController.LogSessionRest)
+	sessions.POST("/:id/finish", sessionController.FinishSession)
+	sessions.DELETE("/:id", sessionController.AbandonSession)
+}
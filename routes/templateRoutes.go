@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"fitness-backend/controllers"
+	"fitness-backend/middleware"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// RegisterTemplateRoutes sets up the workout template routes
+func RegisterTemplateRoutes(e *echo.Echo, db *mongo.Database) {
+	// Controllers
+	templateController := controllers.NewTemplateController(db)
+
+	// Protected API routes
+	api := e.Group("/api")
+	api.Use(middleware.AuthMiddleware(db))
+
+	templates := api.Group("/templates")
+	templates.POST("", templateController.CreateTemplate)
+	templates.GET("", templateController.GetTemplates)
+	templates.GET("/:id", templateController.GetTemplate)
+	templates.PUT("/:id", templateController.UpdateTemplate)
+	templates.DELETE("/:id", templateController.DeleteTemplate)
+	// :date accepts YYYY-MM-DD or "today", resolved in the user's time zone
+	templates.POST("/:id/apply/:date", templateController.ApplyTemplate)
+}
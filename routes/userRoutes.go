@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"fitness-backend/controllers"
+	"fitness-backend/middleware"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// RegisterUserRoutes sets up the profile routes
+func RegisterUserRoutes(e *echo.Echo, db *mongo.Database) {
+	// Controllers
+	userController := controllers.NewUserController(db)
+
+	// Protected API routes
+	api := e.Group("/api")
+	api.Use(middleware.AuthMiddleware(db))
+
+	// Profile routes
+	api.PATCH("/user", userController.UpdateProfile)
+	api.GET("/user/history", userController.GetProfileHistory)
+	api.GET("/user/metrics", userController.GetMetrics)
+	api.POST("/user/metrics/calorie-goal/:date", userController.ApplyCalorieGoal)
+
+	// Equipment profiles for filtering exercises by location
+	api.GET("/user/equipment", userController.GetEquipmentProfiles)
+	api.PUT("/user/equipment/:location", userController.UpdateEquipmentProfile)
+}
@@ -0,0 +1,107 @@
+// Package units converts between the canonical units values are stored in and the
+// units a user prefers to see.
+package units
+
+// Canonical units used for storage
+const (
+	Kilogram    = "kg"
+	Kilometer   = "kms"
+	Liter       = "L"
+	Gram        = "g"
+	Centimeter  = "cm"
+	Kilocalorie = "kcal"
+)
+
+// Imperial display units
+const (
+	Pound      = "lbs"
+	Mile       = "miles"
+	FluidOunce = "fl oz"
+	Ounce      = "oz"
+	Inch       = "in"
+)
+
+// Unit systems a user can prefer
+const (
+	Metric   = "metric"
+	Imperial = "imperial"
+)
+
+// conversion describes a unit in terms of its canonical unit
+type conversion struct {
+	canonical string
+	factor    float64 // multiply by factor to get the canonical value
+}
+
+var conversions = map[string]conversion{
+	Kilogram:   {Kilogram, 1},
+	Pound:      {Kilogram, 0.45359237},
+	Kilometer:  {Kilometer, 1},
+	Mile:       {Kilometer, 1.609344},
+	Liter:      {Liter, 1},
+	FluidOunce: {Liter, 0.0295735295625},
+	Gram:       {Gram, 1},
+	Ounce:      {Gram, 28.349523125},
+	Centimeter: {Centimeter, 1},
+	Inch:       {Centimeter, 2.54},
+}
+
+// imperialUnits maps a canonical unit to its imperial display unit
+var imperialUnits = map[string]string{
+	Kilogram:   Pound,
+	Kilometer:  Mile,
+	Liter:      FluidOunce,
+	Gram:       Ounce,
+	Centimeter: Inch,
+}
+
+// IsConvertible reports whether the unit is known to the converter
+func IsConvertible(unit string) bool {
+	_, ok := conversions[unit]
+	return ok
+}
+
+// Canonical returns the storage unit for a unit, or the unit itself when it is not convertible
+// (e.g. "reps", "mins", "kcal")
+func Canonical(unit string) string {
+	if c, ok := conversions[unit]; ok {
+		return c.canonical
+	}
+	return unit
+}
+
+// ToCanonical converts a value in the given unit to its canonical unit
+func ToCanonical(value float64, unit string) (float64, string) {
+	c, ok := conversions[unit]
+	if !ok {
+		return value, unit
+	}
+	return value * c.factor, c.canonical
+}
+
+// DisplayUnit returns the unit a canonical unit is shown in for a unit system
+func DisplayUnit(canonical, system string) string {
+	if system == Imperial {
+		if unit, ok := imperialUnits[canonical]; ok {
+			return unit
+		}
+	}
+	return canonical
+}
+
+// ToDisplay converts a canonical value into the given unit system
+func ToDisplay(value float64, canonical, system string) (float64, string) {
+	unit := DisplayUnit(canonical, system)
+	return Convert(value, canonical, unit), unit
+}
+
+// Convert converts a value between two units of the same quantity.
+// Values are returned unchanged when the units are unknown or incompatible.
+func Convert(value float64, from, to string) float64 {
+	f, okFrom := conversions[from]
+	t, okTo := conversions[to]
+	if !okFrom || !okTo || f.canonical != t.canonical {
+		return value
+	}
+	return value * f.factor / t.factor
+}
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"math"
+	"time"
+)
+
+// activityMultipliers map an activity level to its TDEE factor
+var activityMultipliers = map[string]float64{
+	"sedentary":   1.2,
+	"light":       1.375,
+	"moderate":    1.55,
+	"active":      1.725,
+	"very_active": 1.9,
+}
+
+// BMI returns the body mass index for a weight in kg and height in cm
+func BMI(weightKg, heightCm float64) float64 {
+	heightM := heightCm / 100
+	return weightKg / (heightM * heightM)
+}
+
+// BMICategory returns the WHO classification for a BMI value
+func BMICategory(bmi float64) string {
+	switch {
+	case bmi < 18.5:
+		return "underweight"
+	case bmi < 25:
+		return "normal"
+	case bmi < 30:
+		return "overweight"
+	default:
+		return "obese"
+	}
+}
+
+// BMRMifflinStJeor estimates basal metabolic rate in kcal/day.
+// Sex "other" uses the midpoint of the male and female constants.
+func BMRMifflinStJeor(weightKg, heightCm float64, ageYears int, sex string) float64 {
+	bmr := 10*weightKg + 6.25*heightCm - 5*float64(ageYears)
+	switch sex {
+	case "male":
+		return bmr + 5
+	case "female":
+		return bmr - 161
+	default:
+		return bmr - 78
+	}
+}
+
+// BMRKatchMcArdle estimates basal metabolic rate in kcal/day from lean body mass
+func BMRKatchMcArdle(weightKg, bodyFatPercent float64) float64 {
+	leanMass := weightKg * (1 - bodyFatPercent/100)
+	return 370 + 21.6*leanMass
+}
+
+// TDEE scales a BMR by the activity level multiplier. ok is false for unknown levels.
+func TDEE(bmr float64, activityLevel string) (float64, bool) {
+	multiplier, ok := activityMultipliers[activityLevel]
+	if !ok {
+		return 0, false
+	}
+	return bmr * multiplier, true
+}
+
+// AgeOn returns the age in whole years at the given time
+func AgeOn(birthDate, at time.Time) int {
+	age := at.Year() - birthDate.Year()
+	if at.Month() < birthDate.Month() || (at.Month() == birthDate.Month() && at.Day() < birthDate.Day()) {
+		age--
+	}
+	return age
+}
+
+// Round rounds a value to the given number of decimal places
+func Round(value float64, places int) float64 {
+	factor := math.Pow(10, float64(places))
+	return math.Round(value*factor) / factor
+}
@@ -0,0 +1,25 @@
+package utils
+
+const (
+	// SecondsPerRep is the assumed time under effort for one repetition
+	SecondsPerRep = 4.0
+	// KcalPerKgPerKm is the approximate energy cost of covering a kilometre on foot,
+	// which holds across running and walking speeds
+	KcalPerKgPerKm = 1.0
+)
+
+// CaloriesFromMET returns the kcal burned doing an activity of the given MET for a number
+// of minutes: MET x body weight in kg x hours
+func CaloriesFromMET(met, weightKg, minutes float64) float64 {
+	return met * weightKg * minutes / 60
+}
+
+// RepsToMinutes converts a repetition count into minutes of effort
+func RepsToMinutes(reps float64) float64 {
+	return reps * SecondsPerRep / 60
+}
+
+// CaloriesFromDistance returns the kcal burned covering a distance in km
+func CaloriesFromDistance(weightKg, km float64) float64 {
+	return KcalPerKgPerKm * weightKg * km
+}
@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"errors"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// DateLayout is the format of the :date path parameter
+const DateLayout = "2006-01-02"
+
+// TodayAlias can be passed instead of a date to mean the user's current local date
+const TodayAlias = "today"
+
+// UserLocation returns the authenticated user's time zone, falling back to UTC
+func UserLocation(c echo.Context) *time.Location {
+	if name, ok := c.Get("timezone").(string); ok && name != "" {
+		if loc, err := time.LoadLocation(name); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+// CalendarDate returns the calendar date of t in loc as midnight UTC, which is how
+// dates are keyed in daily_data
+func CalendarDate(t time.Time, loc *time.Location) time.Time {
+	local := t.In(loc)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// UserToday returns the user's current local date
+func UserToday(c echo.Context) time.Time {
+	return CalendarDate(time.Now(), UserLocation(c))
+}
+
+// ResolveDate turns a :date value into a daily_data date key. It accepts "2006-01-02"
+// or "today", which is resolved in the user's time zone.
+func ResolveDate(c echo.Context, value string) (time.Time, error) {
+	if value == TodayAlias {
+		return UserToday(c), nil
+	}
+	date, err := time.Parse(DateLayout, value)
+	if err != nil {
+		return time.Time{}, errors.New("invalid date format")
+	}
+	return date, nil
+}
@@ -6,14 +6,38 @@ import (
 	"github.com/golang-jwt/jwt"
 )
 
-func GenerateToken(userId string) (string, error) {
+const (
+	// AccessTokenTTL is the lifetime of a signed access token
+	AccessTokenTTL = 15 * time.Minute
+	// RefreshTokenTTL is the lifetime of an opaque refresh token
+	RefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// AccessTokenClaims holds the values embedded in an access token
+type AccessTokenClaims struct {
+	UserID    string
+	SessionID string
+	Role      string
+	Verified  bool
+	Timezone  string
+	Units     string
+}
+
+func GenerateToken(c AccessTokenClaims) (string, error) {
 	token := jwt.New(jwt.SigningMethodHS256)
 	claims := token.Claims.(jwt.MapClaims)
-	claims["user_id"] = userId
+	claims["user_id"] = c.UserID
+	// "sid" ties the access token to a refresh token family so it can be revoked
+	claims["sid"] = c.SessionID
+	claims["role"] = c.Role
+	claims["verified"] = c.Verified
+	// "tz" and "units" let reads localize responses without a user lookup; writes use the
+	// stored preferences, since these claims can predate a profile change
+	claims["tz"] = c.Timezone
+	claims["units"] = c.Units
 
-	// Set expiration claim to 24 hours from now
 	// "exp" is a standard JWT claim for expiration time
-	claims["exp"] = time.Now().Add(24 * time.Hour).Unix()
+	claims["exp"] = time.Now().Add(AccessTokenTTL).Unix()
 
 	jwtSecret := []byte(GetEnvVariable("JWT_SECRET"))
 	tokenString, err := token.SignedString(jwtSecret)
@@ -0,0 +1,32 @@
+package utils
+
+// MaxRepsForOneRepMax is the most reps a one-rep max is estimated from; beyond it the
+// formulas no longer say much about maximal strength
+const MaxRepsForOneRepMax = 30
+
+// EpleyOneRepMax estimates a one-rep max as weight x (1 + reps/30)
+func EpleyOneRepMax(weight float64, reps int) float64 {
+	return weight * (1 + float64(reps)/30)
+}
+
+// BrzyckiOneRepMax estimates a one-rep max as weight x 36 / (37 - reps)
+func BrzyckiOneRepMax(weight float64, reps int) float64 {
+	return weight * 36 / (37 - float64(reps))
+}
+
+// EstimateOneRepMax estimates the heaviest single lift from a set. A single is its own
+// max; up to 10 reps the Epley and Brzycki estimates are averaged, and above that only
+// Epley is used since Brzycki overshoots at high reps. It returns 0 when there is nothing
+// to estimate from.
+func EstimateOneRepMax(weight float64, reps int) float64 {
+	switch {
+	case weight <= 0 || reps < 1 || reps > MaxRepsForOneRepMax:
+		return 0
+	case reps == 1:
+		return weight
+	case reps <= 10:
+		return (EpleyOneRepMax(weight, reps) + BrzyckiOneRepMax(weight, reps)) / 2
+	default:
+		return EpleyOneRepMax(weight, reps)
+	}
+}
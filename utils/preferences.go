@@ -0,0 +1,11 @@
+package utils
+
+import "github.com/labstack/echo/v4"
+
+// UserUnits returns the authenticated user's preferred unit system, "metric" unless set to "imperial"
+func UserUnits(c echo.Context) string {
+	if system, ok := c.Get("units").(string); ok && system == "imperial" {
+		return system
+	}
+	return "metric"
+}
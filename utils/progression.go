@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+)
+
+// Progression rules
+const (
+	// ProgressionLinear adds a fixed increment to the target after every successful workout
+	ProgressionLinear = "linear"
+	// ProgressionDouble adds reps within a range and adds load once the top of it is reached
+	ProgressionDouble = "double"
+)
+
+// Progression actions
+const (
+	ProgressionStart    = "start"    // No history to progress from
+	ProgressionIncrease = "increase" // Last target was met
+	ProgressionRepeat   = "repeat"   // Last target was missed
+	ProgressionDeload   = "deload"   // Too many misses in a row
+)
+
+// ProgressionRule configures how the next target is derived from past attempts
+type ProgressionRule struct {
+	Rule          string  `json:"rule"`
+	Increment     float64 `json:"increment"`     // Added to the goal value after a success (linear)
+	LoadIncrement float64 `json:"loadIncrement"` // Added to the load, in kg, when it goes up
+	MinReps       int     `json:"minReps"`       // Bottom of the rep range (double)
+	MaxReps       int     `json:"maxReps"`       // Top of the rep range (double)
+	DeloadAfter   int     `json:"deloadAfter"`   // Misses in a row before a deload; 0 never deloads
+	DeloadPercent float64 `json:"deloadPercent"` // How much a deload takes off the target and load
+}
+
+// ProgressionAttempt is one past workout of an exercise. Load fields are zero when no sets
+// with weight were logged.
+type ProgressionAttempt struct {
+	Date     time.Time `json:"date"`
+	Target   float64   `json:"target"`
+	Achieved float64   `json:"achieved"`
+	Load     float64   `json:"load"`     // Heaviest weight lifted, in kg
+	LoadSets int       `json:"loadSets"` // Sets done at that weight
+	LoadReps int       `json:"loadReps"` // Fewest reps in those sets
+}
+
+// Met reports whether the attempt reached its target
+func (a ProgressionAttempt) Met() bool {
+	return a.Achieved >= a.Target
+}
+
+// ProgressionSuggestion is the next target for an exercise and why
+type ProgressionSuggestion struct {
+	Action    string  `json:"action"`
+	GoalValue float64 `json:"goalValue"`
+	Load      float64 `json:"load,omitempty"` // Weight to use, in kg
+	Reps      int     `json:"reps,omitempty"` // Reps per set (double progression)
+	Sets      int     `json:"sets,omitempty"`
+	Misses    int     `json:"misses"` // Misses in a row before this suggestion
+	Reason    string  `json:"reason"`
+}
+
+// SuggestNextTarget applies a progression rule to past attempts, oldest first
+func SuggestNextTarget(history []ProgressionAttempt, rule ProgressionRule) ProgressionSuggestion {
+	if len(history) == 0 {
+		return ProgressionSuggestion{Action: ProgressionStart, Reason: "No past workouts of this exercise to progress from"}
+	}
+	last := history[len(history)-1]
+
+	misses := 0
+	for i := len(history) - 1; i >= 0 && !history[i].Met(); i-- {
+		misses++
+	}
+
+	if rule.DeloadAfter > 0 && misses >= rule.DeloadAfter {
+		factor := 1 - rule.DeloadPercent/100
+		return ProgressionSuggestion{
+			Action:    ProgressionDeload,
+			GoalValue: Round(last.Target*factor, 2),
+			Load:      Round(last.Load*factor, 2),
+			Sets:      last.LoadSets,
+			Misses:    misses,
+			Reason:    fmt.Sprintf("Missed the target %d times in a row; dropping %.0f%% to rebuild", misses, rule.DeloadPercent),
+		}
+	}
+
+	if rule.Rule == ProgressionDouble && last.Load > 0 && last.LoadSets > 0 {
+		return doubleProgression(last, rule, misses)
+	}
+
+	if misses > 0 {
+		return ProgressionSuggestion{
+			Action:    ProgressionRepeat,
+			GoalValue: last.Target,
+			Load:      last.Load,
+			Sets:      last.LoadSets,
+			Misses:    misses,
+			Reason:    "Last target was missed; repeat it",
+		}
+	}
+	if last.Load > 0 && rule.LoadIncrement > 0 {
+		return ProgressionSuggestion{
+			Action:    ProgressionIncrease,
+			GoalValue: last.Target,
+			Load:      Round(last.Load+rule.LoadIncrement, 2),
+			Sets:      last.LoadSets,
+			Reason:    "Last target was met; add load",
+		}
+	}
+	return ProgressionSuggestion{
+		Action:    ProgressionIncrease,
+		GoalValue: Round(last.Target+rule.Increment, 2),
+		Load:      last.Load,
+		Sets:      last.LoadSets,
+		Reason:    "Last target was met; raise the target",
+	}
+}
+
+// doubleProgression adds a rep per set until every set reaches the top of the rep range,
+// then adds load and goes back to the bottom of the range
+func doubleProgression(last ProgressionAttempt, rule ProgressionRule, misses int) ProgressionSuggestion {
+	suggestion := ProgressionSuggestion{Load: last.Load, Sets: last.LoadSets, Misses: misses}
+	switch {
+	case last.LoadReps >= rule.MaxReps:
+		suggestion.Action = ProgressionIncrease
+		suggestion.Load = Round(last.Load+rule.LoadIncrement, 2)
+		suggestion.Reps = rule.MinReps
+		suggestion.Reason = fmt.Sprintf("Every set reached %d reps; add load and restart at %d", rule.MaxReps, rule.MinReps)
+	case last.LoadReps < rule.MinReps:
+		suggestion.Action = ProgressionRepeat
+		suggestion.Reps = rule.MinReps
+		suggestion.Reason = fmt.Sprintf("Sets fell short of %d reps; repeat the load", rule.MinReps)
+	default:
+		suggestion.Action = ProgressionIncrease
+		suggestion.Reps = last.LoadReps + 1
+		suggestion.Reason = "Add a rep to every set at the same load"
+	}
+	suggestion.GoalValue = float64(suggestion.Reps * suggestion.Sets)
+	return suggestion
+}
@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSuggestNextTarget(t *testing.T) {
+	day := func(n int) time.Time { return time.Date(2024, 1, n, 0, 0, 0, 0, time.UTC) }
+	met := func(n int, target float64) ProgressionAttempt {
+		return ProgressionAttempt{Date: day(n), Target: target, Achieved: target}
+	}
+	missed := func(n int, target float64) ProgressionAttempt {
+		return ProgressionAttempt{Date: day(n), Target: target, Achieved: target - 1}
+	}
+	lifted := func(n int, load float64, sets, reps int) ProgressionAttempt {
+		target := float64(sets * reps)
+		return ProgressionAttempt{Date: day(n), Target: target, Achieved: target, Load: load, LoadSets: sets, LoadReps: reps}
+	}
+
+	linear := ProgressionRule{Rule: ProgressionLinear, Increment: 2, DeloadAfter: 3, DeloadPercent: 10}
+	double := ProgressionRule{Rule: ProgressionDouble, LoadIncrement: 2.5, MinReps: 8, MaxReps: 12}
+
+	tests := []struct {
+		name    string
+		history []ProgressionAttempt
+		rule    ProgressionRule
+		want    ProgressionSuggestion
+	}{
+		{
+			name: "no history",
+			rule: linear,
+			want: ProgressionSuggestion{Action: ProgressionStart},
+		},
+		{
+			name:    "met target raises it",
+			history: []ProgressionAttempt{met(1, 20)},
+			rule:    linear,
+			want:    ProgressionSuggestion{Action: ProgressionIncrease, GoalValue: 22},
+		},
+		{
+			name:    "met target with load adds load",
+			history: []ProgressionAttempt{{Date: day(1), Target: 30, Achieved: 30, Load: 40, LoadSets: 3, LoadReps: 10}},
+			rule:    ProgressionRule{Rule: ProgressionLinear, Increment: 2, LoadIncrement: 2.5},
+			want:    ProgressionSuggestion{Action: ProgressionIncrease, GoalValue: 30, Load: 42.5, Sets: 3},
+		},
+		{
+			name:    "miss repeats the target",
+			history: []ProgressionAttempt{met(1, 20), missed(2, 22)},
+			rule:    linear,
+			want:    ProgressionSuggestion{Action: ProgressionRepeat, GoalValue: 22, Misses: 1},
+		},
+		{
+			name:    "only trailing misses count",
+			history: []ProgressionAttempt{missed(1, 20), missed(2, 20), met(3, 20), missed(4, 22), missed(5, 22)},
+			rule:    linear,
+			want:    ProgressionSuggestion{Action: ProgressionRepeat, GoalValue: 22, Misses: 2},
+		},
+		{
+			name:    "misses reaching the limit deload",
+			history: []ProgressionAttempt{missed(1, 20), missed(2, 20), missed(3, 20)},
+			rule:    linear,
+			want:    ProgressionSuggestion{Action: ProgressionDeload, GoalValue: 18, Misses: 3},
+		},
+		{
+			name: "deload takes off load too",
+			history: []ProgressionAttempt{
+				{Date: day(1), Target: 30, Achieved: 24, Load: 50, LoadSets: 3, LoadReps: 8},
+				{Date: day(2), Target: 30, Achieved: 25, Load: 50, LoadSets: 3, LoadReps: 8},
+			},
+			rule: ProgressionRule{Rule: ProgressionDouble, MinReps: 10, MaxReps: 12, DeloadAfter: 2, DeloadPercent: 20},
+			want: ProgressionSuggestion{Action: ProgressionDeload, GoalValue: 24, Load: 40, Sets: 3, Misses: 2},
+		},
+		{
+			name:    "no deload limit repeats forever",
+			history: []ProgressionAttempt{missed(1, 20), missed(2, 20), missed(3, 20), missed(4, 20)},
+			rule:    ProgressionRule{Rule: ProgressionLinear, Increment: 2, DeloadPercent: 10},
+			want:    ProgressionSuggestion{Action: ProgressionRepeat, GoalValue: 20, Misses: 4},
+		},
+		{
+			name:    "double adds a rep inside the range",
+			history: []ProgressionAttempt{lifted(1, 40, 3, 8)},
+			rule:    double,
+			want:    ProgressionSuggestion{Action: ProgressionIncrease, GoalValue: 27, Load: 40, Reps: 9, Sets: 3},
+		},
+		{
+			name:    "double reaches the top of the range",
+			history: []ProgressionAttempt{lifted(1, 40, 3, 11)},
+			rule:    double,
+			want:    ProgressionSuggestion{Action: ProgressionIncrease, GoalValue: 36, Load: 40, Reps: 12, Sets: 3},
+		},
+		{
+			name:    "double adds load at the top of the range",
+			history: []ProgressionAttempt{lifted(1, 40, 3, 12)},
+			rule:    double,
+			want:    ProgressionSuggestion{Action: ProgressionIncrease, GoalValue: 24, Load: 42.5, Reps: 8, Sets: 3},
+		},
+		{
+			name:    "double adds load above the range",
+			history: []ProgressionAttempt{lifted(1, 40, 4, 15)},
+			rule:    double,
+			want:    ProgressionSuggestion{Action: ProgressionIncrease, GoalValue: 32, Load: 42.5, Reps: 8, Sets: 4},
+		},
+		{
+			name:    "double repeats below the range",
+			history: []ProgressionAttempt{{Date: day(1), Target: 24, Achieved: 18, Load: 40, LoadSets: 3, LoadReps: 6}},
+			rule:    double,
+			want:    ProgressionSuggestion{Action: ProgressionRepeat, GoalValue: 24, Load: 40, Reps: 8, Sets: 3, Misses: 1},
+		},
+		{
+			name:    "double without load falls back to the target",
+			history: []ProgressionAttempt{met(1, 20)},
+			rule:    ProgressionRule{Rule: ProgressionDouble, Increment: 5, MinReps: 8, MaxReps: 12},
+			want:    ProgressionSuggestion{Action: ProgressionIncrease, GoalValue: 25},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SuggestNextTarget(tt.history, tt.rule)
+			got.Reason = ""
+			if got != tt.want {
+				t.Errorf("SuggestNextTarget() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
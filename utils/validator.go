@@ -0,0 +1,180 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+// FieldError describes a single failed validation rule
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors is returned when one or more fields fail validation
+type ValidationErrors []FieldError
+
+func (ve ValidationErrors) Error() string {
+	messages := make([]string, len(ve))
+	for i, fe := range ve {
+		messages[i] = fe.Field + " " + fe.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+var validate = newValidate()
+
+func newValidate() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	// Report fields by their JSON names so errors match the request body
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+	// numbertype requires an actual number. Untyped map values need it ahead of range rules,
+	// which would otherwise compare a string's length: "number" accepts numeric strings.
+	v.RegisterValidation("numbertype", func(fl validator.FieldLevel) bool {
+		switch fl.Field().Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			return true
+		default:
+			return false
+		}
+	})
+	return v
+}
+
+// CustomValidator plugs struct-tag validation into Echo so handlers can call c.Validate
+type CustomValidator struct{}
+
+// NewValidator returns the validator to assign to echo.Echo.Validator
+func NewValidator() *CustomValidator {
+	return &CustomValidator{}
+}
+
+func (cv *CustomValidator) Validate(i interface{}) error {
+	return ValidateStruct(i)
+}
+
+// ValidateStruct checks the validate tags on a struct and returns ValidationErrors on failure
+func ValidateStruct(i interface{}) error {
+	err := validate.Struct(i)
+	if err == nil {
+		return nil
+	}
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		return err
+	}
+
+	result := make(ValidationErrors, 0, len(fieldErrors))
+	for _, fe := range fieldErrors {
+		// Drop the top-level struct name from the namespace, e.g. "User.email" -> "email"
+		field := fe.Namespace()
+		if idx := strings.Index(field, "."); idx >= 0 {
+			field = field[idx+1:]
+		}
+		result = append(result, FieldError{Field: field, Rule: fe.Tag(), Message: validationMessage(fe.Tag(), fe.Param(), fe.Kind())})
+	}
+	return result
+}
+
+// ValidateMap checks the keys of a loosely typed body (like a partial goal update) against per-key rules
+func ValidateMap(data map[string]interface{}, rules map[string]string) error {
+	keys := make([]string, 0, len(rules))
+	for key := range rules {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var result ValidationErrors
+	for _, key := range keys {
+		value, ok := data[key]
+		if !ok {
+			continue
+		}
+		var fieldErrors validator.ValidationErrors
+		if err := validate.Var(value, rules[key]); errors.As(err, &fieldErrors) {
+			for _, fe := range fieldErrors {
+				result = append(result, FieldError{Field: key, Rule: fe.Tag(), Message: validationMessage(fe.Tag(), fe.Param(), fe.Kind())})
+			}
+		} else if err != nil {
+			return err
+		}
+	}
+	if len(result) > 0 {
+		return result
+	}
+	return nil
+}
+
+// ValidationErrorResponse creates a JSON error response listing every failed field
+func ValidationErrorResponse(err error) echo.Map {
+	var fieldErrors ValidationErrors
+	if errors.As(err, &fieldErrors) {
+		return echo.Map{"error": "Validation failed", "fields": fieldErrors}
+	}
+	return echo.Map{"error": err.Error()}
+}
+
+func validationMessage(tag, param string, kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		if tag == "min" {
+			return fmt.Sprintf("must be at least %s characters long", param)
+		} else if tag == "max" {
+			return fmt.Sprintf("must be at most %s characters long", param)
+		}
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if tag == "min" {
+			return fmt.Sprintf("must contain at least %s items", param)
+		} else if tag == "max" {
+			return fmt.Sprintf("must contain at most %s items", param)
+		}
+	}
+
+	switch tag {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "url":
+		return "must be a valid URL"
+	case "min", "gte":
+		return fmt.Sprintf("must be greater than or equal to %s", param)
+	case "max", "lte":
+		return fmt.Sprintf("must be less than or equal to %s", param)
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", param)
+	case "lt":
+		return fmt.Sprintf("must be less than %s", param)
+	case "gtefield":
+		// param is the Go field name; JSON names are the same with a lowercase first letter
+		return fmt.Sprintf("must be greater than or equal to %s", strings.ToLower(param[:1])+param[1:])
+	case "oneof":
+		return fmt.Sprintf("must be one of [%s]", param)
+	case "timezone":
+		return "must be a valid IANA time zone such as Asia/Kolkata"
+	case "datetime":
+		return fmt.Sprintf("must be a date in the format %s", param)
+	case "numeric", "number", "numbertype":
+		return "must be a number"
+	default:
+		return fmt.Sprintf("failed the %s rule", tag)
+	}
+}